@@ -0,0 +1,464 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// OrganizationOptions configures a run across the accounts of an AWS Organization
+type OrganizationOptions struct {
+	Enabled     bool
+	OUs         []string
+	AccountIDs  []string
+	RoleName    string
+	ExternalID  string
+	SessionName string
+}
+
+func (o OrganizationOptions) validate() error {
+	if !o.Enabled {
+		if len(o.OUs) > 0 || len(o.AccountIDs) > 0 {
+			return errors.New("--org-ous and --accounts need --org")
+		}
+		return nil
+	}
+	if o.RoleName == "" {
+		return errors.New("--role-name is required with --org")
+	}
+	return nil
+}
+
+// Account is an active member account of the organization
+type Account struct {
+	ID   string
+	Name string
+	Arn  string
+}
+
+func (a Account) String() string {
+	if a.Name == "" {
+		return a.ID
+	}
+	return fmt.Sprintf("%s (%s)", a.ID, a.Name)
+}
+
+func newAccount(account orgtypes.Account) Account {
+	return Account{
+		ID:   aws.ToString(account.Id),
+		Name: aws.ToString(account.Name),
+		Arn:  aws.ToString(account.Arn),
+	}
+}
+
+// List every account directly in an OU or in any OU nested below it
+func listAccountsInOU(ctx context.Context, client OrganizationsAPI, ouID string) ([]orgtypes.Account, error) {
+	var accounts []orgtypes.Account
+	accountPaginator := organizations.NewListAccountsForParentPaginator(client, &organizations.ListAccountsForParentInput{
+		ParentId: aws.String(ouID),
+	})
+	for accountPaginator.HasMorePages() {
+		resp, err := accountPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list accounts in %s: %w", ouID, err)
+		}
+		accounts = append(accounts, resp.Accounts...)
+	}
+
+	ouPaginator := organizations.NewListOrganizationalUnitsForParentPaginator(client, &organizations.ListOrganizationalUnitsForParentInput{
+		ParentId: aws.String(ouID),
+	})
+	for ouPaginator.HasMorePages() {
+		resp, err := ouPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list organizational units in %s: %w", ouID, err)
+		}
+		for _, ou := range resp.OrganizationalUnits {
+			nested, err := listAccountsInOU(ctx, client, aws.ToString(ou.Id))
+			if err != nil {
+				return nil, err
+			}
+			accounts = append(accounts, nested...)
+		}
+	}
+
+	return accounts, nil
+}
+
+// List the organization's active accounts, limited to the given OUs and
+// account IDs when either is set
+func listAccounts(ctx context.Context, client OrganizationsAPI, ous, accountIDs []string) ([]Account, error) {
+	var found []orgtypes.Account
+	if len(ous) == 0 {
+		paginator := organizations.NewListAccountsPaginator(client, &organizations.ListAccountsInput{})
+		for paginator.HasMorePages() {
+			resp, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list accounts: %w", err)
+			}
+			found = append(found, resp.Accounts...)
+		}
+	}
+	for _, ou := range ous {
+		accounts, err := listAccountsInOU(ctx, client, ou)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, accounts...)
+	}
+
+	accounts := []Account{}
+	for _, account := range found {
+		if account.Status != orgtypes.AccountStatusActive {
+			continue
+		}
+		if len(accountIDs) > 0 && !slices.Contains(accountIDs, aws.ToString(account.Id)) {
+			continue
+		}
+		if slices.ContainsFunc(accounts, func(a Account) bool { return a.ID == aws.ToString(account.Id) }) {
+			continue
+		}
+		accounts = append(accounts, newAccount(account))
+	}
+	return accounts, nil
+}
+
+// Build the ARN of the role to assume in an account, using the partition from
+// the account's own ARN
+func roleARN(account Account, roleName string) string {
+	partition := "aws"
+	if parts := strings.SplitN(account.Arn, ":", 3); len(parts) == 3 && parts[1] != "" {
+		partition = parts[1]
+	}
+	return fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, account.ID, roleName)
+}
+
+// Return a copy of cfg whose credentials come from assuming the role in account
+func assumeRoleConfig(cfg aws.Config, account Account, opts OrganizationOptions) aws.Config {
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN(account, opts.RoleName), func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = opts.SessionName
+		if opts.ExternalID != "" {
+			o.ExternalID = aws.String(opts.ExternalID)
+		}
+	})
+
+	accountCfg := cfg.Copy()
+	accountCfg.Credentials = aws.NewCredentialsCache(provider)
+	return accountCfg
+}
+
+// runBounded calls fn for every index in [0, n) with at most limit calls in
+// flight at once
+func runBounded(n, limit int, fn func(i int)) {
+	if limit < 1 {
+		limit = 1
+	}
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// accountTarget is an account with its clients and regions resolved
+type accountTarget struct {
+	Account   Account
+	NewClient ClientFactory
+	Regions   regionSet
+	Err       error
+}
+
+// Resolve the client factory and regions for each account. An account whose
+// role can't be assumed is kept with Err set so it shows up in the report.
+func prepareAccounts(ctx context.Context, accounts []Account, newAccountClient func(Account) ClientFactory, homeRegion string, selection RegionSelection, concurrency int) []accountTarget {
+	targets := make([]accountTarget, len(accounts))
+	runBounded(len(accounts), concurrency, func(i int) {
+		target := accountTarget{Account: accounts[i], NewClient: newAccountClient(accounts[i])}
+		target.Regions, target.Err = selectRegions(ctx, target.NewClient(homeRegion), selection)
+		if target.Err != nil {
+			target.Err = fmt.Errorf("account %s: %w", accounts[i].ID, target.Err)
+		}
+		targets[i] = target
+	})
+	return targets
+}
+
+// forEachAccountRegion calls fn for every account and region pair with at
+// most concurrency calls in flight across all accounts, passing a ctx whose
+// logger carries the account ID. Accounts that failed to prepare are left
+// out.
+func forEachAccountRegion(ctx context.Context, targets []accountTarget, concurrency int, fn func(ctx context.Context, target, region int)) {
+	type pair struct{ target, region int }
+	var pairs []pair
+	for t, target := range targets {
+		if target.Err != nil {
+			continue
+		}
+		for r := range target.Regions.Regions {
+			pairs = append(pairs, pair{t, r})
+		}
+	}
+
+	runBounded(len(pairs), concurrency, func(i int) {
+		target := targets[pairs[i].target]
+		fn(withLogAttrs(ctx, logKeyAccount, target.Account.ID), pairs[i].target, pairs[i].region)
+	})
+}
+
+// AccountResult is the outcome for one account. Err is set when the account
+// could not be processed at all, such as when the role could not be assumed.
+type AccountResult struct {
+	Account Account
+	Result  *RunResult
+	Err     error
+}
+
+// SweepResult is the consolidated outcome of a run across accounts
+type SweepResult struct {
+	Accounts []AccountResult
+}
+
+// Err joins every account failure, or returns nil if there were none
+func (s *SweepResult) Err() error {
+	var errs []error
+	for _, account := range s.Accounts {
+		if account.Err != nil {
+			errs = append(errs, account.Err)
+		}
+		if account.Result != nil {
+			if err := account.Result.Err(); err != nil {
+				errs = append(errs, fmt.Errorf("account %s: %w", account.Account.ID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PlanAccounts builds the plan for every account and region
+func PlanAccounts(ctx context.Context, targets []accountTarget, protection Protection, cleanup CleanupOptions, concurrency int) [][]RegionPlan {
+	plans := make([][]RegionPlan, len(targets))
+	for t, target := range targets {
+		plans[t] = make([]RegionPlan, len(target.Regions.Regions))
+	}
+
+	forEachAccountRegion(ctx, targets, concurrency, func(ctx context.Context, t, r int) {
+		region := targets[t].Regions.Regions[r]
+		plans[t][r] = planRegion(ctx, targets[t].NewClient(region), region, protection, cleanup)
+	})
+	return plans
+}
+
+// PreflightAccounts runs the DryRun permission checks for every account and
+// region, returning an error if any account would fail
+func PreflightAccounts(ctx context.Context, targets []accountTarget, protection Protection, cleanup CleanupOptions, concurrency int) ([][]RegionPreflight, error) {
+	reports := make([][]RegionPreflight, len(targets))
+	for t, target := range targets {
+		reports[t] = make([]RegionPreflight, len(target.Regions.Regions))
+	}
+
+	forEachAccountRegion(ctx, targets, concurrency, func(ctx context.Context, t, r int) {
+		region := targets[t].Regions.Regions[r]
+		client := targets[t].NewClient(region)
+		reports[t][r] = preflightRegion(ctx, client, planRegion(ctx, client, region, protection, cleanup))
+	})
+
+	var errs []error
+	for t, accountReports := range reports {
+		for _, report := range accountReports {
+			if !report.OK() {
+				errs = append(errs, fmt.Errorf("account %s region %s failed preflight", targets[t].Account.ID, report.Region))
+			}
+		}
+	}
+	return reports, errors.Join(errs...)
+}
+
+// SweepAccounts deletes the default VPCs in every account and region,
+// snapshotting each region with the account's writer from newBackup first
+func SweepAccounts(ctx context.Context, targets []accountTarget, protection Protection, cleanup CleanupOptions, concurrency int, newBackup func(Account) SnapshotWriter) (*SweepResult, error) {
+	backups := make([]SnapshotWriter, len(targets))
+	result := &SweepResult{Accounts: make([]AccountResult, len(targets))}
+	for t, target := range targets {
+		result.Accounts[t] = AccountResult{Account: target.Account, Err: target.Err}
+		if target.Err == nil {
+			backups[t] = newBackup(target.Account)
+			result.Accounts[t].Result = &RunResult{
+				Regions: make([]RegionResult, len(target.Regions.Regions)),
+				Skipped: target.Regions.Skipped,
+			}
+		}
+	}
+
+	forEachAccountRegion(ctx, targets, concurrency, func(ctx context.Context, t, r int) {
+		region := targets[t].Regions.Regions[r]
+		result.Accounts[t].Result.Regions[r] = deleteRegionDefaultVPCs(ctx, targets[t].NewClient(region), region, protection, cleanup, backups[t])
+	})
+	return result, result.Err()
+}
+
+// Write the consolidated summary, one section per account
+func printSweepSummary(w io.Writer, result *SweepResult) {
+	for _, account := range result.Accounts {
+		fmt.Fprintf(w, "Account %s\n", account.Account)
+		if account.Err != nil {
+			fmt.Fprintf(w, "  failed: %v\n", account.Err)
+			continue
+		}
+		printSummary(w, account.Result)
+	}
+}
+
+// Process every selected account in the organization by assuming a role in
+// each, recording the outcome in report
+func runOrganization(ctx context.Context, cfg aws.Config, opts runOptions, report *Report) error {
+	orgClient := &OrganizationsClient{Client: organizations.NewFromConfig(cfg)}
+	accounts, err := listAccounts(ctx, orgClient, opts.Organization.OUs, opts.Organization.AccountIDs)
+	if err != nil {
+		return err
+	}
+	if len(accounts) == 0 {
+		return errors.New("no active accounts matched")
+	}
+
+	homeRegion := cfg.Region
+	if homeRegion == "" {
+		homeRegion = "us-east-1"
+	}
+	targets := prepareAccounts(ctx, accounts, func(account Account) ClientFactory {
+		return withRetry(NewClientFactory(assumeRoleConfig(cfg, account, opts.Organization), opts.RateLimiter.apiOption()), opts.Retry)
+	}, homeRegion, opts.Regions, opts.Concurrency)
+	for _, target := range targets {
+		report.Accounts = append(report.Accounts, newAccountReport(target.Account, target.Regions.Skipped, target.Err))
+	}
+
+	switch opts.Command {
+	case cmdList, cmdVerify:
+		inventories := InventoryAccounts(ctx, targets, opts.Protection, opts.Concurrency)
+		verify := opts.Command == cmdVerify
+		var errs []error
+		for t, target := range targets {
+			fmt.Fprintf(opts.Out, "Account %s\n", target.Account)
+			if target.Err != nil {
+				fmt.Fprintf(opts.Out, "  Error: %v\n", target.Err)
+				errs = append(errs, target.Err)
+				continue
+			}
+			report.Accounts[t].addInventory(inventories[t], verify)
+			if verify {
+				printVerify(opts.Out, inventories[t])
+			} else {
+				printInventory(opts.Out, inventories[t])
+			}
+			for _, inventory := range inventories[t] {
+				err := inventory.Err
+				if verify {
+					err = inventory.Remaining()
+				}
+				if err != nil {
+					errs = append(errs, fmt.Errorf("account %s: %w", target.Account.ID, err))
+				}
+			}
+		}
+		return errors.Join(errs...)
+	case cmdPlan:
+		plans := PlanAccounts(ctx, targets, opts.Protection, opts.Cleanup, opts.Concurrency)
+		var errs []error
+		for t, target := range targets {
+			fmt.Fprintf(opts.Out, "Account %s\n", target.Account)
+			if target.Err != nil {
+				fmt.Fprintf(opts.Out, "  Error: %v\n", target.Err)
+				errs = append(errs, target.Err)
+				continue
+			}
+			printPlan(opts.Out, plans[t])
+			report.Accounts[t].addPlans(plans[t])
+			for _, plan := range plans[t] {
+				if plan.Err != nil {
+					errs = append(errs, plan.Err)
+				}
+			}
+		}
+		if len(errs) > 0 || opts.PlanOut == "" {
+			return errors.Join(errs...)
+		}
+		var planned []PlannedAccount
+		for t, target := range targets {
+			planned = append(planned, newPlannedAccount(target.Account, plans[t]))
+		}
+		return savePlan(opts.Out, opts.PlanOut, planned)
+	}
+
+	if !opts.SkipPreflight {
+		reports, err := PreflightAccounts(ctx, targets, opts.Protection, opts.Cleanup, opts.Concurrency)
+		for t, target := range targets {
+			if target.Err == nil {
+				fmt.Fprintf(opts.Out, "Account %s\n", target.Account)
+				printPreflight(opts.Out, reports[t])
+			}
+		}
+		if err != nil {
+			for t, accountReports := range reports {
+				for _, regionReport := range accountReports {
+					if !regionReport.OK() {
+						report.Accounts[t].Status = statusFailed
+					}
+				}
+			}
+			return fmt.Errorf("preflight failed, nothing was deleted: %w", err)
+		}
+	}
+
+	if !opts.Yes {
+		inventories := InventoryAccounts(ctx, targets, opts.Protection, opts.Concurrency)
+		if slices.ContainsFunc(inventories, mayDeleteVPCs) {
+			callerID, err := callerAccountID(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			caller := Account{ID: callerID, Name: accountAlias(ctx, cfg)}
+			err = confirmApply(opts.Out, callerID, confirmDelete, func(w io.Writer) {
+				fmt.Fprintf(w, "Organization accessed from account %s\n", caller)
+				for t, target := range targets {
+					if target.Err == nil {
+						fmt.Fprintf(w, "Account %s\n", target.Account)
+						printInventory(w, inventories[t])
+					}
+				}
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	takenAt := time.Now()
+	result, err := SweepAccounts(ctx, targets, opts.Protection, opts.Cleanup, opts.Concurrency, func(account Account) SnapshotWriter {
+		return newSnapshotWriter(opts.SnapshotDir, account.ID, takenAt)
+	})
+	printSweepSummary(opts.Out, result)
+	for t, account := range result.Accounts {
+		if account.Result != nil {
+			report.Accounts[t].addResult(account.Result)
+		}
+	}
+	return err
+}
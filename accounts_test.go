@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+)
+
+func orgAccount(id string, status orgtypes.AccountStatus) orgtypes.Account {
+	return orgtypes.Account{
+		Id:     aws.String(id),
+		Name:   aws.String("account-" + id),
+		Arn:    aws.String("arn:aws:organizations::111111111111:account/o-example/" + id),
+		Status: status,
+	}
+}
+
+func Test_listAccounts(t *testing.T) {
+	client := &MockOrganizationsClient{
+		listAccountsFunc: func(ctx context.Context, input *organizations.ListAccountsInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsOutput, error) {
+			if input.NextToken == nil {
+				return &organizations.ListAccountsOutput{
+					Accounts: []orgtypes.Account{
+						orgAccount("222222222222", orgtypes.AccountStatusActive),
+						orgAccount("333333333333", orgtypes.AccountStatusSuspended),
+					},
+					NextToken: aws.String("page-2"),
+				}, nil
+			}
+			return &organizations.ListAccountsOutput{
+				Accounts: []orgtypes.Account{orgAccount("444444444444", orgtypes.AccountStatusActive)},
+			}, nil
+		},
+		listAccountsForParentFunc: func(ctx context.Context, input *organizations.ListAccountsForParentInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsForParentOutput, error) {
+			switch *input.ParentId {
+			case "ou-parent":
+				return &organizations.ListAccountsForParentOutput{
+					Accounts: []orgtypes.Account{orgAccount("555555555555", orgtypes.AccountStatusActive)},
+				}, nil
+			case "ou-child":
+				return &organizations.ListAccountsForParentOutput{
+					Accounts: []orgtypes.Account{orgAccount("666666666666", orgtypes.AccountStatusActive)},
+				}, nil
+			}
+			return nil, fmt.Errorf("unexpected parent %s", *input.ParentId)
+		},
+		listOrganizationalUnitsForParentFunc: func(ctx context.Context, input *organizations.ListOrganizationalUnitsForParentInput, optFns ...func(*organizations.Options)) (*organizations.ListOrganizationalUnitsForParentOutput, error) {
+			if *input.ParentId == "ou-parent" {
+				return &organizations.ListOrganizationalUnitsForParentOutput{
+					OrganizationalUnits: []orgtypes.OrganizationalUnit{{Id: aws.String("ou-child")}},
+				}, nil
+			}
+			return &organizations.ListOrganizationalUnitsForParentOutput{}, nil
+		},
+	}
+
+	tests := []struct {
+		name       string
+		ous        []string
+		accountIDs []string
+		want       []string
+	}{
+		{
+			name: "whole organization skips suspended accounts",
+			want: []string{"222222222222", "444444444444"},
+		},
+		{
+			name:       "filtered by account ID",
+			accountIDs: []string{"444444444444"},
+			want:       []string{"444444444444"},
+		},
+		{
+			name: "OU includes nested OUs",
+			ous:  []string{"ou-parent"},
+			want: []string{"555555555555", "666666666666"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accounts, err := listAccounts(context.Background(), client, tt.ous, tt.accountIDs)
+			if err != nil {
+				t.Fatalf("listAccounts() error = %v", err)
+			}
+			got := []string{}
+			for _, account := range accounts {
+				got = append(got, account.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("listAccounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_roleARN(t *testing.T) {
+	tests := []struct {
+		name    string
+		account Account
+		want    string
+	}{
+		{
+			name:    "commercial partition",
+			account: Account{ID: "222222222222", Arn: "arn:aws:organizations::111111111111:account/o-example/222222222222"},
+			want:    "arn:aws:iam::222222222222:role/OrganizationAccountAccessRole",
+		},
+		{
+			name:    "GovCloud partition",
+			account: Account{ID: "222222222222", Arn: "arn:aws-us-gov:organizations::111111111111:account/o-example/222222222222"},
+			want:    "arn:aws-us-gov:iam::222222222222:role/OrganizationAccountAccessRole",
+		},
+		{
+			name:    "missing ARN",
+			account: Account{ID: "222222222222"},
+			want:    "arn:aws:iam::222222222222:role/OrganizationAccountAccessRole",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := roleARN(tt.account, "OrganizationAccountAccessRole"); got != tt.want {
+				t.Errorf("roleARN() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_runBounded(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight, calls := 0, 0, 0
+
+	runBounded(20, 3, func(i int) {
+		mu.Lock()
+		inFlight++
+		calls++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	})
+
+	if calls != 20 {
+		t.Errorf("runBounded() made %d calls, want 20", calls)
+	}
+	if maxInFlight > 3 {
+		t.Errorf("runBounded() had %d calls in flight, want at most 3", maxInFlight)
+	}
+}
+
+func TestSweepAccounts(t *testing.T) {
+	emptyRegion := &MockEC2Client{
+		describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+			return &ec2.DescribeVpcsOutput{Vpcs: []types.Vpc{}}, nil
+		},
+	}
+
+	targets := []accountTarget{
+		{
+			Account:   Account{ID: "222222222222"},
+			NewClient: func(region string) EC2API { return emptyRegion },
+			Regions:   regionSet{Regions: []string{"us-east-1", "us-west-2"}},
+		},
+		{
+			Account: Account{ID: "333333333333"},
+			Err:     fmt.Errorf("account 333333333333: AccessDenied"),
+		},
+	}
+
+	result, err := SweepAccounts(context.Background(), targets, Protection{}, CleanupOptions{}, 2, func(account Account) SnapshotWriter {
+		return func(snapshot RegionSnapshot) (string, error) {
+			t.Errorf("SweepAccounts() saved a snapshot for account %s with no default VPCs", account.ID)
+			return "", nil
+		}
+	})
+	if err == nil {
+		t.Fatalf("SweepAccounts() expected an error for the account that could not be prepared")
+	}
+	if got := len(result.Accounts); got != 2 {
+		t.Fatalf("SweepAccounts() returned %d accounts, want 2", got)
+	}
+	if got := len(result.Accounts[0].Result.Regions); got != 2 {
+		t.Errorf("SweepAccounts() processed %d regions, want 2", got)
+	}
+	if result.Accounts[1].Result != nil {
+		t.Errorf("SweepAccounts() processed an account that failed to prepare")
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Describe the egress-only internet gateways attached to a VPC. EC2 can't
+// filter them by VPC, so every gateway in the region is listed.
+func describeEgressOnlyInternetGateways(ctx context.Context, client EC2API, vpcID string) ([]types.EgressOnlyInternetGateway, error) {
+	var gateways []types.EgressOnlyInternetGateway
+	paginator := ec2.NewDescribeEgressOnlyInternetGatewaysPaginator(client, &ec2.DescribeEgressOnlyInternetGatewaysInput{})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe egress-only internet gateways: %w", err)
+		}
+		for _, gateway := range resp.EgressOnlyInternetGateways {
+			for _, attachment := range gateway.Attachments {
+				if aws.ToString(attachment.VpcId) == vpcID {
+					gateways = append(gateways, gateway)
+					break
+				}
+			}
+		}
+	}
+	return gateways, nil
+}
+
+// vpcCidrAssociations lists the CIDR blocks that have to be disassociated
+// from a VPC before it can be deleted: every IPv6 block and every IPv4 block
+// but the primary one
+func vpcCidrAssociations(vpc types.Vpc) []string {
+	var ids []string
+	for _, association := range vpc.CidrBlockAssociationSet {
+		if aws.ToString(association.CidrBlock) == aws.ToString(vpc.CidrBlock) || !vpcCidrAssociated(association.CidrBlockState) {
+			continue
+		}
+		ids = append(ids, aws.ToString(association.AssociationId))
+	}
+	for _, association := range vpc.Ipv6CidrBlockAssociationSet {
+		if vpcCidrAssociated(association.Ipv6CidrBlockState) {
+			ids = append(ids, aws.ToString(association.AssociationId))
+		}
+	}
+	return ids
+}
+
+func vpcCidrAssociated(state *types.VpcCidrBlockState) bool {
+	return state == nil || state.State == types.VpcCidrBlockStateCodeAssociated || state.State == types.VpcCidrBlockStateCodeAssociating
+}
+
+// subnetIpv6Associations lists the IPv6 blocks associated with a subnet
+func subnetIpv6Associations(subnet types.Subnet) []string {
+	var ids []string
+	for _, association := range subnet.Ipv6CidrBlockAssociationSet {
+		state := association.Ipv6CidrBlockState
+		if state == nil || state.State == types.SubnetCidrBlockStateCodeAssociated || state.State == types.SubnetCidrBlockStateCodeAssociating {
+			ids = append(ids, aws.ToString(association.AssociationId))
+		}
+	}
+	return ids
+}
+
+// Delete an egress-only internet gateway
+func deleteEgressOnlyInternetGateway(ctx context.Context, client EC2API, gatewayID string) error {
+	_, err := client.DeleteEgressOnlyInternetGateway(ctx, &ec2.DeleteEgressOnlyInternetGatewayInput{EgressOnlyInternetGatewayId: aws.String(gatewayID)})
+	return err
+}
+
+// Disassociate an IPv6 block from a subnet
+func disassociateSubnetCidrBlock(ctx context.Context, client EC2API, associationID string) error {
+	_, err := client.DisassociateSubnetCidrBlock(ctx, &ec2.DisassociateSubnetCidrBlockInput{AssociationId: aws.String(associationID)})
+	return err
+}
+
+// Disassociate a secondary IPv4 block or an IPv6 block from a VPC
+func disassociateVpcCidrBlock(ctx context.Context, client EC2API, associationID string) error {
+	_, err := client.DisassociateVpcCidrBlock(ctx, &ec2.DisassociateVpcCidrBlockInput{AssociationId: aws.String(associationID)})
+	return err
+}
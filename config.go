@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"gopkg.in/yaml.v3"
+)
+
+// configEnv names the config file when --config isn't given
+const configEnv = "REMOVE_DEFAULT_VPC_CONFIG"
+
+var accountIDPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
+// fileConfig is the YAML config file. Every setting matches a flag, and
+// settings left out keep the flag's default. Flags given on the command line
+// win over the file.
+type fileConfig struct {
+	SkipPreflight *bool `yaml:"skipPreflight"`
+	Regions       struct {
+		Include []string `yaml:"include"`
+		Exclude []string `yaml:"exclude"`
+		All     *bool    `yaml:"all"`
+	} `yaml:"regions"`
+	Protect struct {
+		VPCs []string          `yaml:"vpcs"`
+		Tags map[string]string `yaml:"tags"`
+	} `yaml:"protect"`
+	Cleanup struct {
+		NatGateways  *bool `yaml:"natGateways"`
+		VpcEndpoints *bool `yaml:"vpcEndpoints"`
+	} `yaml:"cleanup"`
+	Concurrency *int `yaml:"concurrency"`
+	RateLimit   struct {
+		PerSecond *float64 `yaml:"perSecond"`
+		Burst     *int     `yaml:"burst"`
+	} `yaml:"rateLimit"`
+	Retry struct {
+		MaxAttempts *int     `yaml:"maxAttempts"`
+		BaseDelay   *string  `yaml:"baseDelay"`
+		MaxDelay    *string  `yaml:"maxDelay"`
+		Codes       []string `yaml:"codes"`
+	} `yaml:"retry"`
+	Output struct {
+		Report      *string `yaml:"report"`
+		ReportFile  *string `yaml:"reportFile"`
+		LogFormat   *string `yaml:"logFormat"`
+		LogLevel    *string `yaml:"logLevel"`
+		SnapshotDir *string `yaml:"snapshotDir"`
+	} `yaml:"output"`
+	Organization struct {
+		Enabled     *bool    `yaml:"enabled"`
+		OUs         []string `yaml:"ous"`
+		Accounts    []string `yaml:"accounts"`
+		RoleName    *string  `yaml:"roleName"`
+		ExternalID  *string  `yaml:"externalId"`
+		SessionName *string  `yaml:"sessionName"`
+	} `yaml:"organization"`
+}
+
+// configSetting is one setting from the file: its YAML key, the flag it
+// stands for and the value in the flag's syntax
+type configSetting struct {
+	key   string
+	flag  string
+	value string
+}
+
+// loadConfig reads and validates a config file. Unknown keys are errors so
+// a typo doesn't silently fall back to a default.
+func loadConfig(path string) (fileConfig, error) {
+	var cfg fileConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("config %s: %w", path, err)
+	}
+	var errs []error
+	for _, err := range cfg.validate() {
+		errs = append(errs, fmt.Errorf("config %s: %w", path, err))
+	}
+	return cfg, errors.Join(errs...)
+}
+
+func oneOf(key string, value *string, allowed ...string) error {
+	if value == nil {
+		return nil
+	}
+	for _, a := range allowed {
+		if *value == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is %q, want one of %s", key, *value, strings.Join(allowed, ", "))
+}
+
+func duration(key string, value *string) error {
+	if value == nil {
+		return nil
+	}
+	if d, err := time.ParseDuration(*value); err != nil || d <= 0 {
+		return fmt.Errorf("%s is %q, want a positive duration such as 1s or 500ms", key, *value)
+	}
+	return nil
+}
+
+// validate checks the values the flags can't check by themselves, naming
+// the YAML key at fault. Every problem is returned at once.
+func (c fileConfig) validate() []error {
+	var errs []error
+	add := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	add(oneOf("output.report", c.Output.Report, reportText, reportJSON))
+	add(oneOf("output.logFormat", c.Output.LogFormat, logText, logJSON))
+	add(oneOf("output.logLevel", c.Output.LogLevel, "debug", "info", "warn", "error"))
+	add(duration("retry.baseDelay", c.Retry.BaseDelay))
+	add(duration("retry.maxDelay", c.Retry.MaxDelay))
+	if c.Concurrency != nil && *c.Concurrency < 1 {
+		add(fmt.Errorf("concurrency is %d, want at least 1", *c.Concurrency))
+	}
+	if c.Retry.MaxAttempts != nil && *c.Retry.MaxAttempts < 1 {
+		add(fmt.Errorf("retry.maxAttempts is %d, want at least 1", *c.Retry.MaxAttempts))
+	}
+	if c.RateLimit.PerSecond != nil && *c.RateLimit.PerSecond < 0 {
+		add(fmt.Errorf("rateLimit.perSecond is %v, want 0 for no limit or more", *c.RateLimit.PerSecond))
+	}
+	if c.RateLimit.Burst != nil && *c.RateLimit.Burst < 1 {
+		add(fmt.Errorf("rateLimit.burst is %d, want at least 1", *c.RateLimit.Burst))
+	}
+	for _, pattern := range append(append([]string{}, c.Regions.Include...), c.Regions.Exclude...) {
+		if _, err := matchesAny("", []string{pattern}); err != nil {
+			add(fmt.Errorf("regions: %q is not a valid pattern", pattern))
+		}
+	}
+	for _, id := range c.Organization.Accounts {
+		if !accountIDPattern.MatchString(id) {
+			add(fmt.Errorf("organization.accounts: %q is not a 12 digit account ID", id))
+		}
+	}
+	for key := range c.Protect.Tags {
+		if key == "" {
+			add(errors.New("protect.tags: tag keys can't be empty"))
+		}
+	}
+	return errs
+}
+
+// settings lists everything the file sets, in the flags' own syntax.
+// protect.tags is left out because a tag key or value may hold the commas
+// --protect-tag splits on; protectTags returns those instead.
+func (c fileConfig) settings() []configSetting {
+	var settings []configSetting
+	addBool := func(key, name string, v *bool) {
+		if v != nil {
+			settings = append(settings, configSetting{key, name, strconv.FormatBool(*v)})
+		}
+	}
+	addInt := func(key, name string, v *int) {
+		if v != nil {
+			settings = append(settings, configSetting{key, name, strconv.Itoa(*v)})
+		}
+	}
+	addString := func(key, name string, v *string) {
+		if v != nil {
+			settings = append(settings, configSetting{key, name, *v})
+		}
+	}
+	addList := func(key, name string, v []string) {
+		if v != nil {
+			settings = append(settings, configSetting{key, name, strings.Join(v, ",")})
+		}
+	}
+
+	addBool("skipPreflight", "skip-preflight", c.SkipPreflight)
+	addList("regions.include", "regions", c.Regions.Include)
+	addList("regions.exclude", "exclude-regions", c.Regions.Exclude)
+	addBool("regions.all", "all-regions", c.Regions.All)
+	addList("protect.vpcs", "protect-vpc", c.Protect.VPCs)
+	addBool("cleanup.natGateways", "delete-nat-gateways", c.Cleanup.NatGateways)
+	addBool("cleanup.vpcEndpoints", "delete-vpc-endpoints", c.Cleanup.VpcEndpoints)
+	addInt("concurrency", "concurrency", c.Concurrency)
+	if c.RateLimit.PerSecond != nil {
+		settings = append(settings, configSetting{"rateLimit.perSecond", "rate-limit", strconv.FormatFloat(*c.RateLimit.PerSecond, 'f', -1, 64)})
+	}
+	addInt("rateLimit.burst", "rate-burst", c.RateLimit.Burst)
+	addInt("retry.maxAttempts", "retry-max-attempts", c.Retry.MaxAttempts)
+	addString("retry.baseDelay", "retry-base-delay", c.Retry.BaseDelay)
+	addString("retry.maxDelay", "retry-max-delay", c.Retry.MaxDelay)
+	addList("retry.codes", "retry-codes", c.Retry.Codes)
+	addString("output.report", "report", c.Output.Report)
+	addString("output.reportFile", "report-file", c.Output.ReportFile)
+	addString("output.logFormat", "log-format", c.Output.LogFormat)
+	addString("output.logLevel", "log-level", c.Output.LogLevel)
+	addString("output.snapshotDir", "snapshot-dir", c.Output.SnapshotDir)
+	addBool("organization.enabled", "org", c.Organization.Enabled)
+	addList("organization.ous", "org-ous", c.Organization.OUs)
+	addList("organization.accounts", "accounts", c.Organization.Accounts)
+	addString("organization.roleName", "role-name", c.Organization.RoleName)
+	addString("organization.externalId", "external-id", c.Organization.ExternalID)
+	addString("organization.sessionName", "session-name", c.Organization.SessionName)
+	return settings
+}
+
+// protectTags returns the protect.tags the file sets, sorted by key, or nil
+// if it sets none
+func (c fileConfig) protectTags() []types.Tag {
+	if c.Protect.Tags == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(c.Protect.Tags))
+	for key := range c.Protect.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tags := []types.Tag{}
+	for _, key := range keys {
+		tags = append(tags, types.Tag{Key: aws.String(key), Value: aws.String(c.Protect.Tags[key])})
+	}
+	return tags
+}
+
+// applyConfig loads the config file at path into flags. Flags already set
+// on the command line are left as they are. The file's protect.tags are
+// returned as they are rather than set on --protect-tag, or nil if the file
+// sets none or --protect-tag was given on the command line.
+func applyConfig(flags *flag.FlagSet, path string) ([]types.Tag, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	explicit := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	for _, setting := range cfg.settings() {
+		if explicit[setting.flag] {
+			continue
+		}
+		if err := flags.Set(setting.flag, setting.value); err != nil {
+			return nil, fmt.Errorf("config %s: %s: %w", path, setting.key, err)
+		}
+	}
+	if explicit["protect-tag"] {
+		return nil, nil
+	}
+	return cfg.protectTags(), nil
+}
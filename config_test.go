@@ -0,0 +1,177 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// testFlags declares a few of main's flags on their own FlagSet
+func testFlags() *flag.FlagSet {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Bool("skip-preflight", false, "")
+	flags.String("regions", "", "")
+	flags.String("exclude-regions", "", "")
+	flags.String("protect-tag", "", "")
+	flags.Bool("delete-nat-gateways", false, "")
+	flags.Int("concurrency", 8, "")
+	flags.Duration("retry-base-delay", time.Second, "")
+	flags.String("report", reportText, "")
+	flags.String("accounts", "", "")
+	return flags
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func Test_applyConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		args     []string
+		want     map[string]string
+		wantTags []types.Tag
+		wantErr  string
+	}{
+		{
+			name: "sets flags",
+			config: `
+skipPreflight: true
+regions:
+  include: [eu-*, us-east-1]
+  exclude: [eu-north-1]
+protect:
+  tags:
+    team: payments
+    keep: "true"
+    owners: "a,b"
+cleanup:
+  natGateways: true
+concurrency: 4
+retry:
+  baseDelay: 500ms
+output:
+  report: json
+`,
+			want: map[string]string{
+				"skip-preflight":      "true",
+				"regions":             "eu-*,us-east-1",
+				"exclude-regions":     "eu-north-1",
+				"protect-tag":         "",
+				"delete-nat-gateways": "true",
+				"concurrency":         "4",
+				"retry-base-delay":    "500ms",
+				"report":              "json",
+			},
+			wantTags: []types.Tag{
+				{Key: aws.String("keep"), Value: aws.String("true")},
+				{Key: aws.String("owners"), Value: aws.String("a,b")},
+				{Key: aws.String("team"), Value: aws.String("payments")},
+			},
+		},
+		{
+			name:   "command line protect tags win",
+			config: "protect:\n  tags:\n    team: payments\n",
+			args:   []string{"--protect-tag", "keep=true"},
+			want:   map[string]string{"protect-tag": "keep=true"},
+		},
+		{
+			name:   "command line wins",
+			config: "concurrency: 4\nregions:\n  include: [eu-west-1]\n",
+			args:   []string{"--concurrency", "2"},
+			want:   map[string]string{"concurrency": "2", "regions": "eu-west-1"},
+		},
+		{
+			name:   "empty file keeps defaults",
+			config: "",
+			want:   map[string]string{"concurrency": "8", "report": reportText},
+		},
+		{
+			name:    "unknown key",
+			config:  "regoins:\n  include: [eu-west-1]\n",
+			wantErr: "field regoins not found",
+		},
+		{
+			name:    "wrong type",
+			config:  "concurrency: lots\n",
+			wantErr: "cannot unmarshal",
+		},
+		{
+			name:    "bad duration",
+			config:  "retry:\n  baseDelay: soon\n",
+			wantErr: `retry.baseDelay is "soon"`,
+		},
+		{
+			name:    "bad report format",
+			config:  "output:\n  report: xml\n",
+			wantErr: `output.report is "xml", want one of text, json`,
+		},
+		{
+			name:    "zero concurrency",
+			config:  "concurrency: 0\n",
+			wantErr: "concurrency is 0, want at least 1",
+		},
+		{
+			name:    "bad account ID",
+			config:  "organization:\n  accounts: [\"1234\"]\n",
+			wantErr: `organization.accounts: "1234" is not a 12 digit account ID`,
+		},
+		{
+			name:    "bad region pattern",
+			config:  "regions:\n  exclude: [\"eu-[\"]\n",
+			wantErr: `regions: "eu-[" is not a valid pattern`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := testFlags()
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			path := writeConfig(t, tt.config)
+			tags, err := applyConfig(flags, path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applyConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), path) {
+					t.Errorf("applyConfig() error = %v, want it to name %s", err, path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyConfig() error = %v", err)
+			}
+			for name, want := range tt.want {
+				if got := flags.Lookup(name).Value.String(); got != want {
+					t.Errorf("--%s = %q, want %q", name, got, want)
+				}
+			}
+			if !reflect.DeepEqual(tags, tt.wantTags) {
+				t.Errorf("applyConfig() tags = %v, want %v", tags, tt.wantTags)
+			}
+		})
+	}
+}
+
+func Test_applyConfig_missingFile(t *testing.T) {
+	_, err := applyConfig(testFlags(), filepath.Join(t.TempDir(), "missing.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "missing.yaml") {
+		t.Errorf("applyConfig() error = %v, want one naming the file", err)
+	}
+}
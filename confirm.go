@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"golang.org/x/term"
+)
+
+// isTerminal reports whether f is an interactive terminal rather than a
+// pipe, file or another character device such as /dev/null
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// Look up the account's alias, or "" if it has none or it can't be read
+func accountAlias(ctx context.Context, cfg aws.Config) string {
+	resp, err := iam.NewFromConfig(cfg).ListAccountAliases(ctx, &iam.ListAccountAliasesInput{})
+	if err != nil {
+		loggerFrom(ctx).Debug("failed to look up the account alias", logKeyError, err)
+		return ""
+	}
+	if len(resp.AccountAliases) == 0 {
+		return ""
+	}
+	return resp.AccountAliases[0]
+}
+
+// What the operator is asked to confirm by typing the account ID
+const (
+	confirmDelete  = "delete the default VPCs listed above"
+	confirmRestore = "create default VPCs in the regions listed above"
+)
+
+// confirmAccount asks the operator to type accountID to go ahead with action
+// and fails unless they do. Anything else, including an empty line, cancels
+// the run.
+func confirmAccount(in io.Reader, out io.Writer, accountID, action string) error {
+	fmt.Fprintf(out, "Type the account ID %s to %s: ", accountID, action)
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	if strings.TrimSpace(line) != accountID {
+		return errors.New("the account ID typed did not match, nothing was changed")
+	}
+	return nil
+}
+
+// confirmApply shows what is about to change on out with show and has the
+// operator type accountID on the terminal to go ahead with action
+func confirmApply(out io.Writer, accountID, action string, show func(w io.Writer)) error {
+	show(out)
+	return confirmAccount(os.Stdin, out, accountID, action)
+}
+
+// mayDeleteVPCs reports whether apply may delete anything in these regions:
+// any of them has an unprotected default VPC, or couldn't be listed and so
+// might still have one
+func mayDeleteVPCs(inventories []RegionInventory) bool {
+	for _, inventory := range inventories {
+		if inventory.Err != nil || len(inventory.VPCs) > 0 {
+			return true
+		}
+	}
+	return false
+}
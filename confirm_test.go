@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_confirmAccount(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "matching ID", input: "111111111111\n"},
+		{name: "surrounding spaces", input: "  111111111111  \n"},
+		{name: "no trailing newline", input: "111111111111"},
+		{name: "other account", input: "222222222222\n", wantErr: true},
+		{name: "yes", input: "yes\n", wantErr: true},
+		{name: "empty line", input: "\n", wantErr: true},
+		{name: "end of input", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := confirmAccount(strings.NewReader(tt.input), &out, "111111111111", confirmRestore)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("confirmAccount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(out.String(), "111111111111") || !strings.Contains(out.String(), confirmRestore) {
+				t.Errorf("confirmAccount() prompt = %q, want it to name the account and what is confirmed", out.String())
+			}
+		})
+	}
+}
+
+func Test_mayDeleteVPCs(t *testing.T) {
+	tests := []struct {
+		name        string
+		inventories []RegionInventory
+		want        bool
+	}{
+		{name: "no regions", want: false},
+		{
+			name:        "only protected VPCs",
+			inventories: []RegionInventory{{Region: "us-east-1", Protected: []ProtectedVPC{{VpcID: "vpc-1"}}}},
+			want:        false,
+		},
+		{
+			name:        "unprotected VPC",
+			inventories: []RegionInventory{{Region: "us-east-1"}, {Region: "eu-west-1", VPCs: []string{"vpc-2"}}},
+			want:        true,
+		},
+		{
+			name:        "region that failed to list",
+			inventories: []RegionInventory{{Region: "us-east-1", Err: errors.New("throttled")}},
+			want:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mayDeleteVPCs(tt.inventories); got != tt.want {
+				t.Errorf("mayDeleteVPCs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isTerminal(t *testing.T) {
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	if isTerminal(devNull) {
+		t.Errorf("isTerminal(%s) = true, want false", os.DevNull)
+	}
+}
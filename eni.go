@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+// How long to wait for Lambda to remove the network interfaces a function
+// used, and how often to check. Lambda can take twenty minutes or more to
+// reclaim them after the function is deleted or leaves the VPC.
+const (
+	lambdaENIWaitTimeout  = 30 * time.Minute
+	lambdaENIWaitInterval = 30 * time.Second
+)
+
+// isOrphanedENI reports whether a network interface was left behind with
+// nothing attached, such as by a deleted load balancer or EFS mount target,
+// and can be deleted. Interfaces a service manages are left to the service.
+func isOrphanedENI(eni types.NetworkInterface) bool {
+	return eni.Status == types.NetworkInterfaceStatusAvailable && !aws.ToBool(eni.RequesterManaged)
+}
+
+// isReleasedLambdaENI reports whether a network interface belongs to Lambda
+// and no function uses it any more, so Lambda will remove it on its own. One
+// still in use keeps the VPC in use.
+func isReleasedLambdaENI(eni types.NetworkInterface) bool {
+	return eni.InterfaceType == types.NetworkInterfaceTypeLambda && eni.Status != types.NetworkInterfaceStatusInUse
+}
+
+// Delete a network interface left behind in a subnet
+func deleteNetworkInterface(ctx context.Context, client EC2API, eniID string) error {
+	_, err := client.DeleteNetworkInterface(ctx, &ec2.DeleteNetworkInterfaceInput{NetworkInterfaceId: aws.String(eniID)})
+	return err
+}
+
+// Wait for Lambda to remove one of its network interfaces. If it is still
+// there when the wait times out, the error says what it belongs to.
+func waitForLambdaENI(ctx context.Context, client EC2API, eniID string) error {
+	description := ""
+	loggerFrom(ctx).Info("waiting for Lambda to remove network interface", logKeyResourceType, resourceNetworkInterface, logKeyResourceID, eniID)
+	err := waitUntil(ctx, lambdaENIWaitTimeout, lambdaENIWaitInterval, func() (bool, error) {
+		out, err := client.DescribeNetworkInterfaces(ctx, &ec2.DescribeNetworkInterfacesInput{NetworkInterfaceIds: []string{eniID}})
+		if isNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		for _, eni := range out.NetworkInterfaces {
+			description = aws.ToString(eni.Description)
+		}
+		return len(out.NetworkInterfaces) == 0, nil
+	})
+	if err != nil && description != "" {
+		return fmt.Errorf("%s is still in use: %w", description, err)
+	}
+	return err
+}
+
+// Delete a subnet. If network interfaces still hold it up, the error names
+// whoever is using them.
+func deleteSubnet(ctx context.Context, client EC2API, subnetID string) error {
+	_, err := client.DeleteSubnet(ctx, &ec2.DeleteSubnetInput{SubnetId: aws.String(subnetID)})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "DependencyViolation" {
+		return err
+	}
+
+	enis, describeErr := describeNetworkInterfaces(ctx, client, types.Filter{Name: aws.String("subnet-id"), Values: []string{subnetID}})
+	if describeErr != nil {
+		return err
+	}
+	var owners []string
+	for _, eni := range enis {
+		owner := describeENIOwner(eni)
+		switch {
+		case owner != "":
+		case eni.Attachment != nil && aws.ToString(eni.Attachment.InstanceId) != "":
+			owner = "instance " + aws.ToString(eni.Attachment.InstanceId)
+		default:
+			owner = fmt.Sprintf("network interface %s (%s)", aws.ToString(eni.NetworkInterfaceId), eni.InterfaceType)
+		}
+		owners = append(owners, owner)
+	}
+	if len(owners) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w: still used by %s", err, strings.Join(owners, ", "))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func Test_deleteSubnet(t *testing.T) {
+	pages := map[string]*ec2.DescribeNetworkInterfacesOutput{
+		"": {
+			NetworkInterfaces: []types.NetworkInterface{{
+				NetworkInterfaceId: aws.String("eni-1"),
+				Status:             types.NetworkInterfaceStatusInUse,
+				Attachment:         &types.NetworkInterfaceAttachment{InstanceId: aws.String("i-1")},
+			}},
+			NextToken: aws.String("page-2"),
+		},
+		"page-2": {
+			NetworkInterfaces: []types.NetworkInterface{{
+				NetworkInterfaceId: aws.String("eni-2"),
+				Status:             types.NetworkInterfaceStatusInUse,
+				InterfaceType:      types.NetworkInterfaceTypeLambda,
+			}},
+		},
+	}
+	client := &MockEC2Client{
+		deleteSubnetFunc: func(ctx context.Context, input *ec2.DeleteSubnetInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSubnetOutput, error) {
+			return nil, fakeError("DependencyViolation", "The subnet '%s' has dependencies and cannot be deleted.", aws.ToString(input.SubnetId))
+		},
+		describeNetworkInterfacesFunc: func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+			if len(input.Filters) != 1 || aws.ToString(input.Filters[0].Name) != "subnet-id" || input.Filters[0].Values[0] != "subnet-1" {
+				t.Errorf("DescribeNetworkInterfaces() filters = %v, want subnet-id subnet-1", input.Filters)
+			}
+			return pages[aws.ToString(input.NextToken)], nil
+		},
+	}
+
+	err := deleteSubnet(context.Background(), client, "subnet-1")
+	if got := errorCode(err); got != "DependencyViolation" {
+		t.Fatalf("deleteSubnet() error = %v, want DependencyViolation", err)
+	}
+	if want := "still used by instance i-1, network interface eni-2 (lambda)"; !strings.HasSuffix(err.Error(), want) {
+		t.Errorf("deleteSubnet() error = %q, want it to end with %q", err, want)
+	}
+}
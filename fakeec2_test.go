@@ -0,0 +1,2022 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/smithy-go"
+)
+
+// FakeEC2 is an in-memory EC2 for tests. It models the resources in a VPC
+// and the dependency rules EC2 enforces when deleting them, failing with the
+// same error codes, so whole runs can be tested offline. Every region has
+// its own state; Client returns an EC2API bound to one of them and can be
+// used as a ClientFactory. It is safe for concurrent use.
+type FakeEC2 struct {
+	mu      sync.Mutex
+	regions map[string]*fakeRegion
+	ids     int
+	fail    map[string][]error
+	denied  map[string]bool
+	calls   []string
+}
+
+// fakeRegion is everything FakeEC2 holds for one region
+type fakeRegion struct {
+	zones             []string
+	vpcs              map[string]*types.Vpc
+	subnets           map[string]*types.Subnet
+	routeTables       map[string]*types.RouteTable
+	networkACLs       map[string]*types.NetworkAcl
+	securityGroups    map[string]*types.SecurityGroup
+	internetGateways  map[string]*types.InternetGateway
+	networkInterfaces map[string]*types.NetworkInterface
+	instances         map[string]*types.Instance
+	natGateways       map[string]*types.NatGateway
+	vpcEndpoints      map[string]*types.VpcEndpoint
+	addresses         map[string]*types.Address
+	dhcpOptions       map[string]*types.DhcpOptions
+	loadBalancers     map[string]*elbv2types.LoadBalancer
+
+	egressOnlyInternetGateways map[string]*types.EgressOnlyInternetGateway
+
+	// The association IDs of the secondary and IPv6 blocks that are still
+	// associated, mapped to the VPC or subnet they belong to
+	vpcCidrBlocks    map[string]string
+	subnetCidrBlocks map[string]string
+}
+
+// NewFakeEC2 returns a FakeEC2 without any regions
+func NewFakeEC2() *FakeEC2 {
+	return &FakeEC2{
+		regions: map[string]*fakeRegion{},
+		fail:    map[string][]error{},
+		denied:  map[string]bool{},
+	}
+}
+
+func newFakeRegion(zones []string) *fakeRegion {
+	return &fakeRegion{
+		zones:             zones,
+		vpcs:              map[string]*types.Vpc{},
+		subnets:           map[string]*types.Subnet{},
+		routeTables:       map[string]*types.RouteTable{},
+		networkACLs:       map[string]*types.NetworkAcl{},
+		securityGroups:    map[string]*types.SecurityGroup{},
+		internetGateways:  map[string]*types.InternetGateway{},
+		networkInterfaces: map[string]*types.NetworkInterface{},
+		instances:         map[string]*types.Instance{},
+		natGateways:       map[string]*types.NatGateway{},
+		vpcEndpoints:      map[string]*types.VpcEndpoint{},
+		addresses:         map[string]*types.Address{},
+		dhcpOptions:       map[string]*types.DhcpOptions{},
+		loadBalancers:     map[string]*elbv2types.LoadBalancer{},
+
+		egressOnlyInternetGateways: map[string]*types.EgressOnlyInternetGateway{},
+
+		vpcCidrBlocks:    map[string]string{},
+		subnetCidrBlocks: map[string]string{},
+	}
+}
+
+// fakeError builds the error EC2 returns for a failed call
+func fakeError(code, format string, args ...any) error {
+	return &smithy.GenericAPIError{Code: code, Message: fmt.Sprintf(format, args...), Fault: smithy.FaultClient}
+}
+
+// AddRegion adds an enabled region with the given availability zones
+func (f *FakeEC2) AddRegion(name string, zones ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.regions[name] = newFakeRegion(zones)
+}
+
+// Client returns an EC2API bound to region
+func (f *FakeEC2) Client(region string) EC2API {
+	return &fakeEC2Client{fake: f, region: region}
+}
+
+// FailNext makes the next calls to operation, such as "DeleteSubnet", fail
+// with errs in turn before it behaves normally again
+func (f *FakeEC2) FailNext(operation string, errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fail[operation] = append(f.fail[operation], errs...)
+}
+
+// Deny makes every call to operation fail with UnauthorizedOperation,
+// dry runs included
+func (f *FakeEC2) Deny(operation string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.denied[operation] = true
+}
+
+// Calls lists every call made so far as "region Operation"
+func (f *FakeEC2) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.calls)
+}
+
+// VPCs lists the IDs of the VPCs left in a region
+func (f *FakeEC2) VPCs(region string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return sortedKeys(f.region(region).vpcs)
+}
+
+// Subnets lists the IDs of the subnets in a VPC
+func (f *FakeEC2) Subnets(region, vpcID string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ids []string
+	for _, id := range sortedKeys(f.region(region).subnets) {
+		if aws.ToString(f.region(region).subnets[id].VpcId) == vpcID {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// DefaultSecurityGroup returns the ID of a VPC's default security group
+func (f *FakeEC2) DefaultSecurityGroup(region, vpcID string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, id := range sortedKeys(f.region(region).securityGroups) {
+		sg := f.region(region).securityGroups[id]
+		if aws.ToString(sg.VpcId) == vpcID && isDefaultSecurityGroup(*sg) {
+			return id
+		}
+	}
+	return ""
+}
+
+// Resources counts everything left in a VPC, including the VPC itself and
+// the defaults that go with it
+func (f *FakeEC2) Resources(region, vpcID string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.region(region)
+	n := 0
+	if _, ok := r.vpcs[vpcID]; ok {
+		n++
+	}
+	for _, owner := range r.vpcCidrBlocks {
+		if owner == vpcID {
+			n++
+		}
+	}
+	for _, subnet := range r.subnets {
+		if aws.ToString(subnet.VpcId) == vpcID {
+			n++
+		}
+	}
+	for _, rt := range r.routeTables {
+		if aws.ToString(rt.VpcId) == vpcID {
+			n++
+		}
+	}
+	for _, acl := range r.networkACLs {
+		if aws.ToString(acl.VpcId) == vpcID {
+			n++
+		}
+	}
+	for _, sg := range r.securityGroups {
+		if aws.ToString(sg.VpcId) == vpcID {
+			n++
+		}
+	}
+	for _, igw := range r.internetGateways {
+		if igwAttachedTo(igw, vpcID) {
+			n++
+		}
+	}
+	for _, eni := range r.networkInterfaces {
+		if aws.ToString(eni.VpcId) == vpcID {
+			n++
+		}
+	}
+	for _, nat := range r.natGateways {
+		if aws.ToString(nat.VpcId) == vpcID && nat.State != types.NatGatewayStateDeleted {
+			n++
+		}
+	}
+	for _, endpoint := range r.vpcEndpoints {
+		if aws.ToString(endpoint.VpcId) == vpcID && endpoint.State != types.StateDeleted {
+			n++
+		}
+	}
+	for _, eigw := range r.egressOnlyInternetGateways {
+		if eigwAttachedTo(eigw, vpcID) {
+			n++
+		}
+	}
+	return n
+}
+
+// Addresses lists the allocation IDs of the Elastic IPs in a region
+func (f *FakeEC2) Addresses(region string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return sortedKeys(f.region(region).addresses)
+}
+
+// AddDefaultVPC adds a default VPC laid out the way AWS creates one: a
+// 172.31.0.0/16 VPC with a public /20 default subnet in every zone, an
+// attached internet gateway, a main route table sending 0.0.0.0/0 to it, and
+// the default network ACL and security group
+func (f *FakeEC2) AddDefaultVPC(region string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.addDefaultVPC(f.region(region))
+}
+
+// AddVPC adds a VPC that isn't a default VPC, with nothing in it
+func (f *FakeEC2) AddVPC(region, cidr string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.addVPC(f.region(region), cidr, false)
+}
+
+// AddSubnet adds a subnet to a VPC
+func (f *FakeEC2) AddSubnet(region, vpcID, zone, cidr string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.addSubnet(f.region(region), vpcID, zone, cidr, false)
+}
+
+// AddRouteTable adds a route table to a VPC, associated with subnetIDs
+func (f *FakeEC2) AddRouteTable(region, vpcID string, subnetIDs ...string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.newID("rtb")
+	rt := &types.RouteTable{RouteTableId: aws.String(id), VpcId: aws.String(vpcID)}
+	for _, subnetID := range subnetIDs {
+		rt.Associations = append(rt.Associations, types.RouteTableAssociation{
+			RouteTableAssociationId: aws.String(f.newID("rtbassoc")),
+			RouteTableId:            aws.String(id),
+			SubnetId:                aws.String(subnetID),
+			Main:                    aws.Bool(false),
+		})
+	}
+	f.region(region).routeTables[id] = rt
+	return id
+}
+
+// AddNetworkACL adds a network ACL to a VPC, associated with subnetIDs
+func (f *FakeEC2) AddNetworkACL(region, vpcID string, subnetIDs ...string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.newID("acl")
+	acl := &types.NetworkAcl{NetworkAclId: aws.String(id), VpcId: aws.String(vpcID), IsDefault: aws.Bool(false)}
+	for _, subnetID := range subnetIDs {
+		acl.Associations = append(acl.Associations, types.NetworkAclAssociation{
+			NetworkAclAssociationId: aws.String(f.newID("aclassoc")),
+			NetworkAclId:            aws.String(id),
+			SubnetId:                aws.String(subnetID),
+		})
+	}
+	f.region(region).networkACLs[id] = acl
+	return id
+}
+
+// AddSecurityGroup adds a security group to a VPC
+func (f *FakeEC2) AddSecurityGroup(region, vpcID, name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.addSecurityGroup(f.region(region), vpcID, name)
+}
+
+// AddSecurityGroupReference adds an ingress rule to groupID allowing traffic
+// from referencedID, which then can't be deleted while the rule exists
+func (f *FakeEC2) AddSecurityGroupReference(region, groupID, referencedID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sg := f.region(region).securityGroups[groupID]
+	sg.IpPermissions = append(sg.IpPermissions, types.IpPermission{
+		IpProtocol:       aws.String("-1"),
+		UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: aws.String(referencedID)}},
+	})
+}
+
+// AddNetworkInterface adds an available network interface to a subnet in
+// groupIDs, or the VPC's default security group if none are given
+func (f *FakeEC2) AddNetworkInterface(region, subnetID string, groupIDs ...string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.addNetworkInterface(f.region(region), subnetID, groupIDs)
+}
+
+// AddPublicIP maps a public address to a network interface, which stops
+// the VPC's internet gateway from being detached
+func (f *FakeEC2) AddPublicIP(region, eniID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.region(region).networkInterfaces[eniID].Association = &types.NetworkInterfaceAssociation{PublicIp: aws.String("203.0.113.10")}
+}
+
+// AddLambdaNetworkInterface adds a network interface Lambda created in a
+// subnet for a function that has since gone. Lambda removes it the first
+// time it is described by ID, as if cleanup had waited it out.
+func (f *FakeEC2) AddLambdaNetworkInterface(region, subnetID string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.region(region)
+	id := f.addNetworkInterface(r, subnetID, nil)
+	eni := r.networkInterfaces[id]
+	eni.InterfaceType = types.NetworkInterfaceTypeLambda
+	eni.RequesterManaged = aws.Bool(true)
+	eni.Description = aws.String("AWS Lambda VPC ENI-worker")
+	return id
+}
+
+// AddNatGateway adds an available public NAT gateway to a subnet, with an
+// Elastic IP mapped to its network interface
+func (f *FakeEC2) AddNatGateway(region, subnetID string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.region(region)
+	id := f.newID("nat")
+	eniID := f.addNetworkInterface(r, subnetID, nil)
+	eni := r.networkInterfaces[eniID]
+	eni.Status = types.NetworkInterfaceStatusInUse
+	eni.InterfaceType = types.NetworkInterfaceTypeNatGateway
+	eni.RequesterManaged = aws.Bool(true)
+	eni.Groups = nil
+	eni.Association = &types.NetworkInterfaceAssociation{PublicIp: aws.String("203.0.113.20")}
+
+	allocationID := f.newID("eipalloc")
+	r.addresses[allocationID] = &types.Address{
+		AllocationId:       aws.String(allocationID),
+		AssociationId:      aws.String(f.newID("eipassoc")),
+		NetworkInterfaceId: aws.String(eniID),
+		PublicIp:           aws.String("203.0.113.20"),
+		Domain:             types.DomainTypeVpc,
+	}
+	r.natGateways[id] = &types.NatGateway{
+		NatGatewayId: aws.String(id),
+		VpcId:        eni.VpcId,
+		SubnetId:     aws.String(subnetID),
+		State:        types.NatGatewayStateAvailable,
+		NatGatewayAddresses: []types.NatGatewayAddress{{
+			AllocationId:       aws.String(allocationID),
+			NetworkInterfaceId: aws.String(eniID),
+			PublicIp:           aws.String("203.0.113.20"),
+		}},
+	}
+	return id
+}
+
+// AddVpcEndpoint adds an interface endpoint with a network interface in
+// each of subnetIDs, or a gateway endpoint on the VPC's main route table if
+// no subnets are given
+func (f *FakeEC2) AddVpcEndpoint(region, vpcID string, subnetIDs ...string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.region(region)
+	id := f.newID("vpce")
+	endpoint := &types.VpcEndpoint{
+		VpcEndpointId:   aws.String(id),
+		VpcId:           aws.String(vpcID),
+		VpcEndpointType: types.VpcEndpointTypeGateway,
+		State:           types.StateAvailable,
+	}
+	if len(subnetIDs) == 0 {
+		for _, rtID := range sortedKeys(r.routeTables) {
+			if rt := r.routeTables[rtID]; aws.ToString(rt.VpcId) == vpcID && isMainRouteTable(*rt) {
+				endpoint.RouteTableIds = append(endpoint.RouteTableIds, rtID)
+			}
+		}
+	} else {
+		endpoint.VpcEndpointType = types.VpcEndpointTypeInterface
+		endpoint.SubnetIds = subnetIDs
+	}
+	for _, subnetID := range subnetIDs {
+		eniID := f.addNetworkInterface(r, subnetID, nil)
+		eni := r.networkInterfaces[eniID]
+		eni.Status = types.NetworkInterfaceStatusInUse
+		eni.InterfaceType = types.NetworkInterfaceTypeVpcEndpoint
+		eni.RequesterManaged = aws.Bool(true)
+		endpoint.NetworkInterfaceIds = append(endpoint.NetworkInterfaceIds, eniID)
+		if len(endpoint.Groups) == 0 {
+			for _, g := range eni.Groups {
+				endpoint.Groups = append(endpoint.Groups, types.SecurityGroupIdentifier{GroupId: g.GroupId})
+			}
+		}
+	}
+	r.vpcEndpoints[id] = endpoint
+	return id
+}
+
+// AddEgressOnlyInternetGateway attaches an egress-only internet gateway to
+// a VPC
+func (f *FakeEC2) AddEgressOnlyInternetGateway(region, vpcID string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.newID("eigw")
+	f.region(region).egressOnlyInternetGateways[id] = &types.EgressOnlyInternetGateway{
+		EgressOnlyInternetGatewayId: aws.String(id),
+		Attachments:                 []types.InternetGatewayAttachment{{VpcId: aws.String(vpcID), State: types.AttachmentStatusAttached}},
+	}
+	return id
+}
+
+// AddIPv6CidrBlock associates an Amazon-provided /56 with a VPC and a /64
+// from it with each of the VPC's subnets
+func (f *FakeEC2) AddIPv6CidrBlock(region, vpcID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.region(region)
+	vpc := r.vpcs[vpcID]
+	id := f.newID("vpc-cidr-assoc")
+	r.vpcCidrBlocks[id] = vpcID
+	vpc.Ipv6CidrBlockAssociationSet = append(slices.Clone(vpc.Ipv6CidrBlockAssociationSet), types.VpcIpv6CidrBlockAssociation{
+		AssociationId:      aws.String(id),
+		Ipv6CidrBlock:      aws.String("2600:1f18:1234:5600::/56"),
+		Ipv6CidrBlockState: &types.VpcCidrBlockState{State: types.VpcCidrBlockStateCodeAssociated},
+		Ipv6Pool:           aws.String("Amazon"),
+	})
+	for i, subnetID := range sortedKeys(r.subnets) {
+		subnet := r.subnets[subnetID]
+		if aws.ToString(subnet.VpcId) != vpcID {
+			continue
+		}
+		subnetAssociationID := f.newID("subnet-cidr-assoc")
+		r.subnetCidrBlocks[subnetAssociationID] = subnetID
+		subnet.Ipv6CidrBlockAssociationSet = append(slices.Clone(subnet.Ipv6CidrBlockAssociationSet), types.SubnetIpv6CidrBlockAssociation{
+			AssociationId:      aws.String(subnetAssociationID),
+			Ipv6CidrBlock:      aws.String(fmt.Sprintf("2600:1f18:1234:56%02x::/64", i)),
+			Ipv6CidrBlockState: &types.SubnetCidrBlockState{State: types.SubnetCidrBlockStateCodeAssociated},
+		})
+	}
+}
+
+// AddCidrBlock associates a secondary IPv4 block with a VPC
+func (f *FakeEC2) AddCidrBlock(region, vpcID, cidr string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.region(region)
+	vpc := r.vpcs[vpcID]
+	id := f.newID("vpc-cidr-assoc")
+	r.vpcCidrBlocks[id] = vpcID
+	vpc.CidrBlockAssociationSet = append(slices.Clone(vpc.CidrBlockAssociationSet), types.VpcCidrBlockAssociation{
+		AssociationId:  aws.String(id),
+		CidrBlock:      aws.String(cidr),
+		CidrBlockState: &types.VpcCidrBlockState{State: types.VpcCidrBlockStateCodeAssociated},
+	})
+	return id
+}
+
+// AddInstance launches a running instance with an in-use network interface
+// in a subnet
+func (f *FakeEC2) AddInstance(region, subnetID string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.region(region)
+	id := f.newID("i")
+	eniID := f.addNetworkInterface(r, subnetID, nil)
+	eni := r.networkInterfaces[eniID]
+	eni.Status = types.NetworkInterfaceStatusInUse
+	eni.Attachment = &types.NetworkInterfaceAttachment{InstanceId: aws.String(id)}
+	r.instances[id] = &types.Instance{
+		InstanceId: aws.String(id),
+		VpcId:      eni.VpcId,
+		SubnetId:   aws.String(subnetID),
+		State:      &types.InstanceState{Name: types.InstanceStateNameRunning},
+	}
+	return id
+}
+
+// AddLoadBalancer creates an application load balancer with an in-use
+// network interface in a subnet, returning its ARN
+func (f *FakeEC2) AddLoadBalancer(region, subnetID, name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.region(region)
+	arn := fmt.Sprintf("arn:aws:elasticloadbalancing:%s:123456789012:loadbalancer/app/%s/%s", region, name, f.newID("lb"))
+	eniID := f.addNetworkInterface(r, subnetID, nil)
+	eni := r.networkInterfaces[eniID]
+	eni.Status = types.NetworkInterfaceStatusInUse
+	eni.Description = aws.String("ELB app/" + name)
+	r.loadBalancers[arn] = &elbv2types.LoadBalancer{
+		LoadBalancerArn:  aws.String(arn),
+		LoadBalancerName: aws.String(name),
+		VpcId:            eni.VpcId,
+		Type:             elbv2types.LoadBalancerTypeEnumApplication,
+	}
+	return arn
+}
+
+// region returns a region's state, adding an empty region if needed. The
+// caller holds f.mu.
+func (f *FakeEC2) region(name string) *fakeRegion {
+	r, ok := f.regions[name]
+	if !ok {
+		r = newFakeRegion(nil)
+		f.regions[name] = r
+	}
+	return r
+}
+
+func (f *FakeEC2) newID(prefix string) string {
+	f.ids++
+	return fmt.Sprintf("%s-%017x", prefix, f.ids)
+}
+
+// Add a VPC with the main route table, network ACL and security group
+// every VPC starts with
+func (f *FakeEC2) addVPC(r *fakeRegion, cidr string, isDefault bool) string {
+	vpcID := f.newID("vpc")
+	doptID := f.newID("dopt")
+	r.dhcpOptions[doptID] = &types.DhcpOptions{DhcpOptionsId: aws.String(doptID)}
+	r.vpcs[vpcID] = &types.Vpc{
+		VpcId:         aws.String(vpcID),
+		CidrBlock:     aws.String(cidr),
+		DhcpOptionsId: aws.String(doptID),
+		IsDefault:     aws.Bool(isDefault),
+		State:         types.VpcStateAvailable,
+		CidrBlockAssociationSet: []types.VpcCidrBlockAssociation{{
+			AssociationId:  aws.String(f.newID("vpc-cidr-assoc")),
+			CidrBlock:      aws.String(cidr),
+			CidrBlockState: &types.VpcCidrBlockState{State: types.VpcCidrBlockStateCodeAssociated},
+		}},
+	}
+
+	rtID := f.newID("rtb")
+	r.routeTables[rtID] = &types.RouteTable{
+		RouteTableId: aws.String(rtID),
+		VpcId:        aws.String(vpcID),
+		Associations: []types.RouteTableAssociation{{
+			RouteTableAssociationId: aws.String(f.newID("rtbassoc")),
+			RouteTableId:            aws.String(rtID),
+			Main:                    aws.Bool(true),
+		}},
+		Routes: []types.Route{{DestinationCidrBlock: aws.String(cidr), GatewayId: aws.String("local")}},
+	}
+
+	aclID := f.newID("acl")
+	r.networkACLs[aclID] = &types.NetworkAcl{NetworkAclId: aws.String(aclID), VpcId: aws.String(vpcID), IsDefault: aws.Bool(true)}
+	f.addSecurityGroup(r, vpcID, "default")
+	return vpcID
+}
+
+func (f *FakeEC2) addDefaultVPC(r *fakeRegion) string {
+	vpcID := f.addVPC(r, defaultVPCCidr, true)
+
+	igwID := f.newID("igw")
+	r.internetGateways[igwID] = &types.InternetGateway{
+		InternetGatewayId: aws.String(igwID),
+		Attachments:       []types.InternetGatewayAttachment{{VpcId: aws.String(vpcID), State: types.AttachmentStatusAttached}},
+	}
+	for _, rt := range r.routeTables {
+		if aws.ToString(rt.VpcId) == vpcID {
+			rt.Routes = append(rt.Routes, types.Route{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String(igwID)})
+		}
+	}
+
+	for _, zone := range r.zones {
+		f.addDefaultSubnet(r, vpcID, zone)
+	}
+	return vpcID
+}
+
+// Add a default subnet in zone using the first free /20 in the VPC
+func (f *FakeEC2) addDefaultSubnet(r *fakeRegion, vpcID, zone string) string {
+	used := map[string]bool{}
+	for _, subnet := range r.subnets {
+		if aws.ToString(subnet.VpcId) == vpcID {
+			used[aws.ToString(subnet.CidrBlock)] = true
+		}
+	}
+	for i := 0; ; i++ {
+		cidr := "172.31." + strconv.Itoa(i*16) + ".0/20"
+		if !used[cidr] {
+			return f.addSubnet(r, vpcID, zone, cidr, true)
+		}
+	}
+}
+
+func (f *FakeEC2) addSubnet(r *fakeRegion, vpcID, zone, cidr string, defaultForAz bool) string {
+	id := f.newID("subnet")
+	r.subnets[id] = &types.Subnet{
+		SubnetId:            aws.String(id),
+		VpcId:               aws.String(vpcID),
+		AvailabilityZone:    aws.String(zone),
+		CidrBlock:           aws.String(cidr),
+		DefaultForAz:        aws.Bool(defaultForAz),
+		MapPublicIpOnLaunch: aws.Bool(defaultForAz),
+		State:               types.SubnetStateAvailable,
+	}
+	return id
+}
+
+func (f *FakeEC2) addSecurityGroup(r *fakeRegion, vpcID, name string) string {
+	id := f.newID("sg")
+	r.securityGroups[id] = &types.SecurityGroup{GroupId: aws.String(id), GroupName: aws.String(name), VpcId: aws.String(vpcID)}
+	return id
+}
+
+func (f *FakeEC2) addNetworkInterface(r *fakeRegion, subnetID string, groupIDs []string) string {
+	subnet := r.subnets[subnetID]
+	if len(groupIDs) == 0 {
+		for id, sg := range r.securityGroups {
+			if aws.ToString(sg.VpcId) == aws.ToString(subnet.VpcId) && isDefaultSecurityGroup(*sg) {
+				groupIDs = []string{id}
+			}
+		}
+	}
+	id := f.newID("eni")
+	eni := &types.NetworkInterface{
+		NetworkInterfaceId: aws.String(id),
+		VpcId:              subnet.VpcId,
+		SubnetId:           aws.String(subnetID),
+		Status:             types.NetworkInterfaceStatusAvailable,
+		InterfaceType:      types.NetworkInterfaceTypeInterface,
+	}
+	for _, groupID := range groupIDs {
+		eni.Groups = append(eni.Groups, types.GroupIdentifier{GroupId: aws.String(groupID)})
+	}
+	r.networkInterfaces[id] = eni
+	return id
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// matchFilters reports whether a resource with the given filterable fields
+// passes every filter. A filter the resource doesn't support is an error,
+// as it is in EC2.
+func matchFilters(filters []types.Filter, fields map[string]string) (bool, error) {
+	for _, filter := range filters {
+		name := aws.ToString(filter.Name)
+		value, ok := fields[name]
+		if !ok {
+			return false, fakeError("InvalidParameterValue", "The filter '%s' is invalid", name)
+		}
+		if !slices.Contains(filter.Values, value) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func igwAttachedTo(igw *types.InternetGateway, vpcID string) bool {
+	return slices.ContainsFunc(igw.Attachments, func(a types.InternetGatewayAttachment) bool {
+		return aws.ToString(a.VpcId) == vpcID
+	})
+}
+
+func eigwAttachedTo(eigw *types.EgressOnlyInternetGateway, vpcID string) bool {
+	return slices.ContainsFunc(eigw.Attachments, func(a types.InternetGatewayAttachment) bool {
+		return aws.ToString(a.VpcId) == vpcID
+	})
+}
+
+// fakeEC2Client is a FakeEC2 bound to one region
+type fakeEC2Client struct {
+	fake   *FakeEC2
+	region string
+}
+
+// begin locks the fake and records a call, then returns the error the call
+// should fail with before doing anything: UnauthorizedOperation if it is
+// denied, DryRunOperation for a dry run, or the next queued failure. The
+// caller must unlock f.fake.mu.
+func (c *fakeEC2Client) begin(operation string, dryRun *bool) (*fakeRegion, error) {
+	f := c.fake
+	f.mu.Lock()
+	f.calls = append(f.calls, c.region+" "+operation)
+	switch {
+	case f.denied[operation]:
+		return nil, fakeError("UnauthorizedOperation", "You are not authorized to perform this operation.")
+	case aws.ToBool(dryRun):
+		return nil, fakeError("DryRunOperation", "Request would have succeeded, but DryRun flag is set.")
+	case len(f.fail[operation]) > 0:
+		err := f.fail[operation][0]
+		f.fail[operation] = f.fail[operation][1:]
+		return nil, err
+	}
+	return f.region(c.region), nil
+}
+
+func (c *fakeEC2Client) DescribeRegions(ctx context.Context, input *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error) {
+	_, err := c.begin("DescribeRegions", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	out := &ec2.DescribeRegionsOutput{}
+	for _, name := range sortedKeys(c.fake.regions) {
+		out.Regions = append(out.Regions, types.Region{RegionName: aws.String(name), OptInStatus: aws.String("opt-in-not-required")})
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) DescribeAvailabilityZones(ctx context.Context, input *ec2.DescribeAvailabilityZonesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeAvailabilityZonesOutput, error) {
+	r, err := c.begin("DescribeAvailabilityZones", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	out := &ec2.DescribeAvailabilityZonesOutput{}
+	for _, zone := range r.zones {
+		ok, err := matchFilters(input.Filters, map[string]string{"zone-type": "availability-zone", "state": "available", "zone-name": zone})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.AvailabilityZones = append(out.AvailabilityZones, types.AvailabilityZone{ZoneName: aws.String(zone), RegionName: aws.String(c.region), State: types.AvailabilityZoneStateAvailable})
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) DescribeVpcs(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+	r, err := c.begin("DescribeVpcs", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range input.VpcIds {
+		if _, ok := r.vpcs[id]; !ok {
+			return nil, fakeError("InvalidVpcID.NotFound", "The vpc ID '%s' does not exist", id)
+		}
+	}
+	out := &ec2.DescribeVpcsOutput{}
+	for _, id := range sortedKeys(r.vpcs) {
+		vpc := r.vpcs[id]
+		if len(input.VpcIds) > 0 && !slices.Contains(input.VpcIds, id) {
+			continue
+		}
+		ok, err := matchFilters(input.Filters, map[string]string{"vpc-id": id, "is-default": strconv.FormatBool(aws.ToBool(vpc.IsDefault))})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.Vpcs = append(out.Vpcs, *vpc)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) DescribeSubnets(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	r, err := c.begin("DescribeSubnets", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	out := &ec2.DescribeSubnetsOutput{}
+	for _, id := range sortedKeys(r.subnets) {
+		subnet := r.subnets[id]
+		ok, err := matchFilters(input.Filters, map[string]string{
+			"vpc-id":            aws.ToString(subnet.VpcId),
+			"subnet-id":         id,
+			"availability-zone": aws.ToString(subnet.AvailabilityZone),
+			"default-for-az":    strconv.FormatBool(aws.ToBool(subnet.DefaultForAz)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.Subnets = append(out.Subnets, *subnet)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) DescribeRouteTables(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+	r, err := c.begin("DescribeRouteTables", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	out := &ec2.DescribeRouteTablesOutput{}
+	for _, id := range sortedKeys(r.routeTables) {
+		rt := r.routeTables[id]
+		ok, err := matchFilters(input.Filters, map[string]string{
+			"vpc-id":           aws.ToString(rt.VpcId),
+			"route-table-id":   id,
+			"association.main": strconv.FormatBool(isMainRouteTable(*rt)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.RouteTables = append(out.RouteTables, *rt)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) DescribeInternetGateways(ctx context.Context, input *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
+	r, err := c.begin("DescribeInternetGateways", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	out := &ec2.DescribeInternetGatewaysOutput{}
+	for _, id := range sortedKeys(r.internetGateways) {
+		igw := r.internetGateways[id]
+		attachedTo := ""
+		if len(igw.Attachments) > 0 {
+			attachedTo = aws.ToString(igw.Attachments[0].VpcId)
+		}
+		ok, err := matchFilters(input.Filters, map[string]string{"attachment.vpc-id": attachedTo, "internet-gateway-id": id})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.InternetGateways = append(out.InternetGateways, *igw)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) DescribeEgressOnlyInternetGateways(ctx context.Context, input *ec2.DescribeEgressOnlyInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+	r, err := c.begin("DescribeEgressOnlyInternetGateways", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	out := &ec2.DescribeEgressOnlyInternetGatewaysOutput{}
+	for _, id := range sortedKeys(r.egressOnlyInternetGateways) {
+		if len(input.EgressOnlyInternetGatewayIds) > 0 && !slices.Contains(input.EgressOnlyInternetGatewayIds, id) {
+			continue
+		}
+		ok, err := matchFilters(input.Filters, map[string]string{})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.EgressOnlyInternetGateways = append(out.EgressOnlyInternetGateways, *r.egressOnlyInternetGateways[id])
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) DescribeNetworkAcls(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
+	r, err := c.begin("DescribeNetworkAcls", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	out := &ec2.DescribeNetworkAclsOutput{}
+	for _, id := range sortedKeys(r.networkACLs) {
+		acl := r.networkACLs[id]
+		ok, err := matchFilters(input.Filters, map[string]string{
+			"vpc-id":         aws.ToString(acl.VpcId),
+			"network-acl-id": id,
+			"default":        strconv.FormatBool(aws.ToBool(acl.IsDefault)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.NetworkAcls = append(out.NetworkAcls, *acl)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) DescribeSecurityGroups(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	r, err := c.begin("DescribeSecurityGroups", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range input.GroupIds {
+		if _, ok := r.securityGroups[id]; !ok {
+			return nil, fakeError("InvalidGroup.NotFound", "The security group '%s' does not exist", id)
+		}
+	}
+	out := &ec2.DescribeSecurityGroupsOutput{}
+	for _, id := range sortedKeys(r.securityGroups) {
+		sg := r.securityGroups[id]
+		if len(input.GroupIds) > 0 && !slices.Contains(input.GroupIds, id) {
+			continue
+		}
+		ok, err := matchFilters(input.Filters, map[string]string{"vpc-id": aws.ToString(sg.VpcId), "group-id": id, "group-name": aws.ToString(sg.GroupName)})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.SecurityGroups = append(out.SecurityGroups, *sg)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) DescribeNetworkInterfaces(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	r, err := c.begin("DescribeNetworkInterfaces", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	out := &ec2.DescribeNetworkInterfacesOutput{}
+	for _, id := range input.NetworkInterfaceIds {
+		eni, ok := r.networkInterfaces[id]
+		if ok && eni.InterfaceType == types.NetworkInterfaceTypeLambda {
+			delete(r.networkInterfaces, id)
+			ok = false
+		}
+		if !ok {
+			return nil, fakeError("InvalidNetworkInterfaceID.NotFound", "The networkInterface ID '%s' does not exist", id)
+		}
+	}
+	for _, id := range sortedKeys(r.networkInterfaces) {
+		eni := r.networkInterfaces[id]
+		if len(input.NetworkInterfaceIds) > 0 && !slices.Contains(input.NetworkInterfaceIds, id) {
+			continue
+		}
+		ok, err := matchFilters(input.Filters, map[string]string{
+			"vpc-id":               aws.ToString(eni.VpcId),
+			"subnet-id":            aws.ToString(eni.SubnetId),
+			"network-interface-id": id,
+			"status":               string(eni.Status),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.NetworkInterfaces = append(out.NetworkInterfaces, *eni)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) DescribeInstances(ctx context.Context, input *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	r, err := c.begin("DescribeInstances", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	reservation := types.Reservation{}
+	for _, id := range sortedKeys(r.instances) {
+		instance := r.instances[id]
+		ok, err := matchFilters(input.Filters, map[string]string{
+			"vpc-id":              aws.ToString(instance.VpcId),
+			"subnet-id":           aws.ToString(instance.SubnetId),
+			"instance-id":         id,
+			"instance-state-name": string(instance.State.Name),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			reservation.Instances = append(reservation.Instances, *instance)
+		}
+	}
+	out := &ec2.DescribeInstancesOutput{}
+	if len(reservation.Instances) > 0 {
+		out.Reservations = []types.Reservation{reservation}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) DescribeNatGateways(ctx context.Context, input *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+	r, err := c.begin("DescribeNatGateways", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	finishNatGatewayDeletes(r)
+	for _, id := range input.NatGatewayIds {
+		if _, ok := r.natGateways[id]; !ok {
+			return nil, fakeError("NatGatewayNotFound", "The Nat Gateway %s was not found", id)
+		}
+	}
+	out := &ec2.DescribeNatGatewaysOutput{}
+	for _, id := range sortedKeys(r.natGateways) {
+		nat := r.natGateways[id]
+		if len(input.NatGatewayIds) > 0 && !slices.Contains(input.NatGatewayIds, id) {
+			continue
+		}
+		ok, err := matchFilters(input.Filter, map[string]string{
+			"vpc-id":         aws.ToString(nat.VpcId),
+			"subnet-id":      aws.ToString(nat.SubnetId),
+			"nat-gateway-id": id,
+			"state":          string(nat.State),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.NatGateways = append(out.NatGateways, *nat)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) DescribeVpcEndpoints(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+	r, err := c.begin("DescribeVpcEndpoints", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	finishVpcEndpointDeletes(r)
+	for _, id := range input.VpcEndpointIds {
+		if _, ok := r.vpcEndpoints[id]; !ok {
+			return nil, fakeError("InvalidVpcEndpointId.NotFound", "The Vpc Endpoint Id '%s' does not exist", id)
+		}
+	}
+	out := &ec2.DescribeVpcEndpointsOutput{}
+	for _, id := range sortedKeys(r.vpcEndpoints) {
+		endpoint := r.vpcEndpoints[id]
+		if len(input.VpcEndpointIds) > 0 && !slices.Contains(input.VpcEndpointIds, id) {
+			continue
+		}
+		ok, err := matchFilters(input.Filters, map[string]string{"vpc-id": aws.ToString(endpoint.VpcId), "vpc-endpoint-id": id})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.VpcEndpoints = append(out.VpcEndpoints, *endpoint)
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) DescribeLoadBalancers(ctx context.Context, input *elbv2.DescribeLoadBalancersInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeLoadBalancersOutput, error) {
+	r, err := c.begin("DescribeLoadBalancers", nil)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	out := &elbv2.DescribeLoadBalancersOutput{}
+	for _, arn := range sortedKeys(r.loadBalancers) {
+		out.LoadBalancers = append(out.LoadBalancers, *r.loadBalancers[arn])
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) DescribeDhcpOptions(ctx context.Context, input *ec2.DescribeDhcpOptionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeDhcpOptionsOutput, error) {
+	r, err := c.begin("DescribeDhcpOptions", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	out := &ec2.DescribeDhcpOptionsOutput{}
+	for _, id := range input.DhcpOptionsIds {
+		dopt, ok := r.dhcpOptions[id]
+		if !ok {
+			return nil, fakeError("InvalidDhcpOptionID.NotFound", "The dhcpOption ID '%s' does not exist", id)
+		}
+		out.DhcpOptions = append(out.DhcpOptions, *dopt)
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) DetachInternetGateway(ctx context.Context, input *ec2.DetachInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DetachInternetGatewayOutput, error) {
+	r, err := c.begin("DetachInternetGateway", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	igwID, vpcID := aws.ToString(input.InternetGatewayId), aws.ToString(input.VpcId)
+	igw, ok := r.internetGateways[igwID]
+	if !ok {
+		return nil, fakeError("InvalidInternetGatewayID.NotFound", "The internetGateway ID '%s' does not exist", igwID)
+	}
+	if _, ok := r.vpcs[vpcID]; !ok {
+		return nil, fakeError("InvalidVpcID.NotFound", "The vpc ID '%s' does not exist", vpcID)
+	}
+	if !igwAttachedTo(igw, vpcID) {
+		return nil, fakeError("Gateway.NotAttached", "resource %s is not attached to network %s", igwID, vpcID)
+	}
+	for _, eni := range r.networkInterfaces {
+		if aws.ToString(eni.VpcId) == vpcID && eni.Association != nil && aws.ToString(eni.Association.PublicIp) != "" {
+			return nil, fakeError("DependencyViolation", "Network %s has some mapped public address(es). Please unmap those public address(es) before detaching the gateway.", vpcID)
+		}
+	}
+	igw.Attachments = nil
+	return &ec2.DetachInternetGatewayOutput{}, nil
+}
+
+func (c *fakeEC2Client) DeleteInternetGateway(ctx context.Context, input *ec2.DeleteInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DeleteInternetGatewayOutput, error) {
+	r, err := c.begin("DeleteInternetGateway", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	igwID := aws.ToString(input.InternetGatewayId)
+	igw, ok := r.internetGateways[igwID]
+	if !ok {
+		return nil, fakeError("InvalidInternetGatewayID.NotFound", "The internetGateway ID '%s' does not exist", igwID)
+	}
+	if len(igw.Attachments) > 0 {
+		return nil, fakeError("DependencyViolation", "The internetGateway '%s' has dependencies and cannot be deleted.", igwID)
+	}
+	delete(r.internetGateways, igwID)
+	return &ec2.DeleteInternetGatewayOutput{}, nil
+}
+
+func (c *fakeEC2Client) DeleteSubnet(ctx context.Context, input *ec2.DeleteSubnetInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSubnetOutput, error) {
+	r, err := c.begin("DeleteSubnet", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	subnetID := aws.ToString(input.SubnetId)
+	if _, ok := r.subnets[subnetID]; !ok {
+		return nil, fakeError("InvalidSubnetID.NotFound", "The subnet ID '%s' does not exist", subnetID)
+	}
+	for _, eni := range r.networkInterfaces {
+		if aws.ToString(eni.SubnetId) == subnetID {
+			return nil, fakeError("DependencyViolation", "The subnet '%s' has dependencies and cannot be deleted.", subnetID)
+		}
+	}
+	for _, rt := range r.routeTables {
+		rt.Associations = slices.DeleteFunc(rt.Associations, func(a types.RouteTableAssociation) bool {
+			return aws.ToString(a.SubnetId) == subnetID
+		})
+	}
+	for _, acl := range r.networkACLs {
+		acl.Associations = slices.DeleteFunc(acl.Associations, func(a types.NetworkAclAssociation) bool {
+			return aws.ToString(a.SubnetId) == subnetID
+		})
+	}
+	for associationID, owner := range r.subnetCidrBlocks {
+		if owner == subnetID {
+			delete(r.subnetCidrBlocks, associationID)
+		}
+	}
+	delete(r.subnets, subnetID)
+	return &ec2.DeleteSubnetOutput{}, nil
+}
+
+func (c *fakeEC2Client) DeleteNetworkInterface(ctx context.Context, input *ec2.DeleteNetworkInterfaceInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error) {
+	r, err := c.begin("DeleteNetworkInterface", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	eniID := aws.ToString(input.NetworkInterfaceId)
+	eni, ok := r.networkInterfaces[eniID]
+	if !ok {
+		return nil, fakeError("InvalidNetworkInterfaceID.NotFound", "The networkInterface ID '%s' does not exist", eniID)
+	}
+	if aws.ToBool(eni.RequesterManaged) {
+		return nil, fakeError("OperationNotPermitted", "You are not allowed to manage '%s' attachments.", eniID)
+	}
+	if eni.Status == types.NetworkInterfaceStatusInUse {
+		return nil, fakeError("InvalidNetworkInterface.InUse", "Interface: [%s] in use.", eniID)
+	}
+	delete(r.networkInterfaces, eniID)
+	return &ec2.DeleteNetworkInterfaceOutput{}, nil
+}
+
+func (c *fakeEC2Client) DeleteEgressOnlyInternetGateway(ctx context.Context, input *ec2.DeleteEgressOnlyInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DeleteEgressOnlyInternetGatewayOutput, error) {
+	r, err := c.begin("DeleteEgressOnlyInternetGateway", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	eigwID := aws.ToString(input.EgressOnlyInternetGatewayId)
+	if _, ok := r.egressOnlyInternetGateways[eigwID]; !ok {
+		return nil, fakeError("InvalidEgressOnlyInternetGatewayId.NotFound", "The egress-only internet gateway ID '%s' does not exist", eigwID)
+	}
+	delete(r.egressOnlyInternetGateways, eigwID)
+	return &ec2.DeleteEgressOnlyInternetGatewayOutput{ReturnCode: aws.Bool(true)}, nil
+}
+
+func (c *fakeEC2Client) DisassociateSubnetCidrBlock(ctx context.Context, input *ec2.DisassociateSubnetCidrBlockInput, optFns ...func(*ec2.Options)) (*ec2.DisassociateSubnetCidrBlockOutput, error) {
+	r, err := c.begin("DisassociateSubnetCidrBlock", nil)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	associationID := aws.ToString(input.AssociationId)
+	subnetID, ok := r.subnetCidrBlocks[associationID]
+	if ok {
+		subnet := r.subnets[subnetID]
+		for i, association := range subnet.Ipv6CidrBlockAssociationSet {
+			if aws.ToString(association.AssociationId) != associationID {
+				continue
+			}
+			delete(r.subnetCidrBlocks, associationID)
+			subnet.Ipv6CidrBlockAssociationSet = slices.Clone(subnet.Ipv6CidrBlockAssociationSet)
+			association.Ipv6CidrBlockState = &types.SubnetCidrBlockState{State: types.SubnetCidrBlockStateCodeDisassociated}
+			subnet.Ipv6CidrBlockAssociationSet[i] = association
+			return &ec2.DisassociateSubnetCidrBlockOutput{SubnetId: aws.String(subnetID), Ipv6CidrBlockAssociation: &association}, nil
+		}
+	}
+	return nil, fakeError("InvalidSubnetCidrBlockAssociationID.NotFound", "The subnet CIDR block association ID '%s' does not exist", associationID)
+}
+
+// DisassociateVpcCidrBlock refuses the VPC's primary block, and a block that
+// subnets still use
+func (c *fakeEC2Client) DisassociateVpcCidrBlock(ctx context.Context, input *ec2.DisassociateVpcCidrBlockInput, optFns ...func(*ec2.Options)) (*ec2.DisassociateVpcCidrBlockOutput, error) {
+	r, err := c.begin("DisassociateVpcCidrBlock", nil)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	associationID := aws.ToString(input.AssociationId)
+	vpcID, ok := r.vpcCidrBlocks[associationID]
+	if !ok {
+		for _, vpc := range r.vpcs {
+			if slices.ContainsFunc(vpc.CidrBlockAssociationSet, func(a types.VpcCidrBlockAssociation) bool {
+				return aws.ToString(a.AssociationId) == associationID && aws.ToString(a.CidrBlock) == aws.ToString(vpc.CidrBlock)
+			}) {
+				return nil, fakeError("OperationNotPermitted", "The vpc CIDR block with association ID %s may not be disassociated. It is the primary IPv4 CIDR block of the VPC", associationID)
+			}
+		}
+		return nil, fakeError("InvalidVpcCidrBlockAssociationID.NotFound", "The vpc CIDR block association ID '%s' does not exist", associationID)
+	}
+	vpc := r.vpcs[vpcID]
+	inUse := fakeError("DependencyViolation", "The vpc CIDR block with association ID %s is in use by subnets", associationID)
+	disassociated := &types.VpcCidrBlockState{State: types.VpcCidrBlockStateCodeDisassociated}
+
+	for i, association := range vpc.CidrBlockAssociationSet {
+		if aws.ToString(association.AssociationId) != associationID {
+			continue
+		}
+		block, err := netip.ParsePrefix(aws.ToString(association.CidrBlock))
+		if err != nil {
+			return nil, err
+		}
+		for _, subnet := range r.subnets {
+			if aws.ToString(subnet.VpcId) != vpcID {
+				continue
+			}
+			if prefix, err := netip.ParsePrefix(aws.ToString(subnet.CidrBlock)); err == nil && block.Contains(prefix.Addr()) {
+				return nil, inUse
+			}
+		}
+		delete(r.vpcCidrBlocks, associationID)
+		vpc.CidrBlockAssociationSet = slices.Clone(vpc.CidrBlockAssociationSet)
+		association.CidrBlockState = disassociated
+		vpc.CidrBlockAssociationSet[i] = association
+		return &ec2.DisassociateVpcCidrBlockOutput{VpcId: aws.String(vpcID), CidrBlockAssociation: &association}, nil
+	}
+	for i, association := range vpc.Ipv6CidrBlockAssociationSet {
+		if aws.ToString(association.AssociationId) != associationID {
+			continue
+		}
+		for _, subnetID := range r.subnetCidrBlocks {
+			if aws.ToString(r.subnets[subnetID].VpcId) == vpcID {
+				return nil, inUse
+			}
+		}
+		delete(r.vpcCidrBlocks, associationID)
+		vpc.Ipv6CidrBlockAssociationSet = slices.Clone(vpc.Ipv6CidrBlockAssociationSet)
+		association.Ipv6CidrBlockState = disassociated
+		vpc.Ipv6CidrBlockAssociationSet[i] = association
+		return &ec2.DisassociateVpcCidrBlockOutput{VpcId: aws.String(vpcID), Ipv6CidrBlockAssociation: &association}, nil
+	}
+	return nil, fakeError("InvalidVpcCidrBlockAssociationID.NotFound", "The vpc CIDR block association ID '%s' does not exist", associationID)
+}
+
+func (c *fakeEC2Client) DeleteRouteTable(ctx context.Context, input *ec2.DeleteRouteTableInput, optFns ...func(*ec2.Options)) (*ec2.DeleteRouteTableOutput, error) {
+	r, err := c.begin("DeleteRouteTable", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	rtID := aws.ToString(input.RouteTableId)
+	rt, ok := r.routeTables[rtID]
+	if !ok {
+		return nil, fakeError("InvalidRouteTableID.NotFound", "The routeTable ID '%s' does not exist", rtID)
+	}
+	if len(rt.Associations) > 0 {
+		return nil, fakeError("DependencyViolation", "The routeTable '%s' has dependencies and cannot be deleted.", rtID)
+	}
+	delete(r.routeTables, rtID)
+	return &ec2.DeleteRouteTableOutput{}, nil
+}
+
+func (c *fakeEC2Client) DeleteNetworkAcl(ctx context.Context, input *ec2.DeleteNetworkAclInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkAclOutput, error) {
+	r, err := c.begin("DeleteNetworkAcl", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	aclID := aws.ToString(input.NetworkAclId)
+	acl, ok := r.networkACLs[aclID]
+	if !ok {
+		return nil, fakeError("InvalidNetworkAclID.NotFound", "The networkAcl ID '%s' does not exist", aclID)
+	}
+	if aws.ToBool(acl.IsDefault) {
+		return nil, fakeError("InvalidParameterValue", "cannot delete default network ACL %s", aclID)
+	}
+	if len(acl.Associations) > 0 {
+		return nil, fakeError("DependencyViolation", "The networkAcl '%s' has dependencies and cannot be deleted.", aclID)
+	}
+	delete(r.networkACLs, aclID)
+	return &ec2.DeleteNetworkAclOutput{}, nil
+}
+
+func (c *fakeEC2Client) DeleteSecurityGroup(ctx context.Context, input *ec2.DeleteSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error) {
+	r, err := c.begin("DeleteSecurityGroup", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	groupID := aws.ToString(input.GroupId)
+	sg, ok := r.securityGroups[groupID]
+	if !ok {
+		return nil, fakeError("InvalidGroup.NotFound", "The security group '%s' does not exist", groupID)
+	}
+	if isDefaultSecurityGroup(*sg) {
+		return nil, fakeError("CannotDelete", "the specified group: \"%s\" name: \"default\" cannot be deleted by a user", groupID)
+	}
+	for _, eni := range r.networkInterfaces {
+		if slices.ContainsFunc(eni.Groups, func(g types.GroupIdentifier) bool { return aws.ToString(g.GroupId) == groupID }) {
+			return nil, fakeError("DependencyViolation", "resource %s has a dependent object", groupID)
+		}
+	}
+	for otherID, other := range r.securityGroups {
+		if otherID != groupID && referencesGroup(*other, groupID) {
+			return nil, fakeError("DependencyViolation", "resource %s has a dependent object", groupID)
+		}
+	}
+	delete(r.securityGroups, groupID)
+	return &ec2.DeleteSecurityGroupOutput{}, nil
+}
+
+func (c *fakeEC2Client) RevokeSecurityGroupIngress(ctx context.Context, input *ec2.RevokeSecurityGroupIngressInput, optFns ...func(*ec2.Options)) (*ec2.RevokeSecurityGroupIngressOutput, error) {
+	r, err := c.begin("RevokeSecurityGroupIngress", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	sg, ok := r.securityGroups[aws.ToString(input.GroupId)]
+	if !ok {
+		return nil, fakeError("InvalidGroup.NotFound", "The security group '%s' does not exist", aws.ToString(input.GroupId))
+	}
+	sg.IpPermissions = revokePermissions(sg.IpPermissions, input.IpPermissions)
+	return &ec2.RevokeSecurityGroupIngressOutput{Return: aws.Bool(true)}, nil
+}
+
+func (c *fakeEC2Client) RevokeSecurityGroupEgress(ctx context.Context, input *ec2.RevokeSecurityGroupEgressInput, optFns ...func(*ec2.Options)) (*ec2.RevokeSecurityGroupEgressOutput, error) {
+	r, err := c.begin("RevokeSecurityGroupEgress", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	sg, ok := r.securityGroups[aws.ToString(input.GroupId)]
+	if !ok {
+		return nil, fakeError("InvalidGroup.NotFound", "The security group '%s' does not exist", aws.ToString(input.GroupId))
+	}
+	sg.IpPermissionsEgress = revokePermissions(sg.IpPermissionsEgress, input.IpPermissions)
+	return &ec2.RevokeSecurityGroupEgressOutput{Return: aws.Bool(true)}, nil
+}
+
+// revokePermissions drops the group references in revoked from permissions,
+// and any rule left with nothing in it. Rules are matched on protocol alone.
+func revokePermissions(permissions, revoked []types.IpPermission) []types.IpPermission {
+	var kept []types.IpPermission
+	for _, permission := range permissions {
+		for _, r := range revoked {
+			if aws.ToString(r.IpProtocol) != aws.ToString(permission.IpProtocol) {
+				continue
+			}
+			permission.UserIdGroupPairs = slices.DeleteFunc(slices.Clone(permission.UserIdGroupPairs), func(pair types.UserIdGroupPair) bool {
+				return slices.ContainsFunc(r.UserIdGroupPairs, func(p types.UserIdGroupPair) bool {
+					return aws.ToString(p.GroupId) == aws.ToString(pair.GroupId)
+				})
+			})
+		}
+		if len(permission.UserIdGroupPairs) > 0 || len(permission.IpRanges) > 0 || len(permission.Ipv6Ranges) > 0 || len(permission.PrefixListIds) > 0 {
+			kept = append(kept, permission)
+		}
+	}
+	return kept
+}
+
+// referencesGroup reports whether any of sg's rules allow traffic from or to
+// groupID
+func referencesGroup(sg types.SecurityGroup, groupID string) bool {
+	for _, permission := range slices.Concat(sg.IpPermissions, sg.IpPermissionsEgress) {
+		for _, pair := range permission.UserIdGroupPairs {
+			if aws.ToString(pair.GroupId) == groupID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// A NAT gateway being deleted finishes by the next describe, taking its
+// network interface with it and leaving its Elastic IP unassociated
+func finishNatGatewayDeletes(r *fakeRegion) {
+	for _, nat := range r.natGateways {
+		if nat.State != types.NatGatewayStateDeleting {
+			continue
+		}
+		nat.State = types.NatGatewayStateDeleted
+		for _, address := range nat.NatGatewayAddresses {
+			delete(r.networkInterfaces, aws.ToString(address.NetworkInterfaceId))
+			if eip, ok := r.addresses[aws.ToString(address.AllocationId)]; ok {
+				eip.AssociationId, eip.NetworkInterfaceId = nil, nil
+			}
+		}
+	}
+}
+
+// A VPC endpoint being deleted finishes by the next describe, taking its
+// network interfaces with it
+func finishVpcEndpointDeletes(r *fakeRegion) {
+	for _, endpoint := range r.vpcEndpoints {
+		if endpoint.State != types.StateDeleting {
+			continue
+		}
+		endpoint.State = types.StateDeleted
+		for _, eniID := range endpoint.NetworkInterfaceIds {
+			delete(r.networkInterfaces, eniID)
+		}
+		endpoint.NetworkInterfaceIds, endpoint.RouteTableIds = nil, nil
+	}
+}
+
+func (c *fakeEC2Client) DeleteNatGateway(ctx context.Context, input *ec2.DeleteNatGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNatGatewayOutput, error) {
+	r, err := c.begin("DeleteNatGateway", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	natID := aws.ToString(input.NatGatewayId)
+	nat, ok := r.natGateways[natID]
+	if !ok || nat.State == types.NatGatewayStateDeleted {
+		return nil, fakeError("NatGatewayNotFound", "The Nat Gateway %s was not found", natID)
+	}
+	nat.State = types.NatGatewayStateDeleting
+	return &ec2.DeleteNatGatewayOutput{NatGatewayId: aws.String(natID)}, nil
+}
+
+func (c *fakeEC2Client) DeleteVpcEndpoints(ctx context.Context, input *ec2.DeleteVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVpcEndpointsOutput, error) {
+	r, err := c.begin("DeleteVpcEndpoints", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	out := &ec2.DeleteVpcEndpointsOutput{}
+	for _, id := range input.VpcEndpointIds {
+		endpoint, ok := r.vpcEndpoints[id]
+		if !ok || endpoint.State == types.StateDeleted {
+			out.Unsuccessful = append(out.Unsuccessful, types.UnsuccessfulItem{
+				ResourceId: aws.String(id),
+				Error: &types.UnsuccessfulItemError{
+					Code:    aws.String("InvalidVpcEndpointId.NotFound"),
+					Message: aws.String(fmt.Sprintf("The Vpc Endpoint Id '%s' does not exist", id)),
+				},
+			})
+			continue
+		}
+		endpoint.State = types.StateDeleting
+	}
+	return out, nil
+}
+
+func (c *fakeEC2Client) ReleaseAddress(ctx context.Context, input *ec2.ReleaseAddressInput, optFns ...func(*ec2.Options)) (*ec2.ReleaseAddressOutput, error) {
+	r, err := c.begin("ReleaseAddress", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	allocationID := aws.ToString(input.AllocationId)
+	address, ok := r.addresses[allocationID]
+	if !ok {
+		return nil, fakeError("InvalidAllocationID.NotFound", "The allocation ID '%s' does not exist", allocationID)
+	}
+	if address.AssociationId != nil {
+		return nil, fakeError("InvalidIPAddress.InUse", "Address %s is in use.", aws.ToString(address.PublicIp))
+	}
+	delete(r.addresses, allocationID)
+	return &ec2.ReleaseAddressOutput{}, nil
+}
+
+func (c *fakeEC2Client) DeleteVpc(ctx context.Context, input *ec2.DeleteVpcInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVpcOutput, error) {
+	r, err := c.begin("DeleteVpc", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	vpcID := aws.ToString(input.VpcId)
+	if _, ok := r.vpcs[vpcID]; !ok {
+		return nil, fakeError("InvalidVpcID.NotFound", "The vpc ID '%s' does not exist", vpcID)
+	}
+
+	dependencyViolation := fakeError("DependencyViolation", "The vpc '%s' has dependencies and cannot be deleted.", vpcID)
+	for _, subnet := range r.subnets {
+		if aws.ToString(subnet.VpcId) == vpcID {
+			return nil, dependencyViolation
+		}
+	}
+	for _, igw := range r.internetGateways {
+		if igwAttachedTo(igw, vpcID) {
+			return nil, dependencyViolation
+		}
+	}
+	for _, eni := range r.networkInterfaces {
+		if aws.ToString(eni.VpcId) == vpcID {
+			return nil, dependencyViolation
+		}
+	}
+	for _, rt := range r.routeTables {
+		if aws.ToString(rt.VpcId) == vpcID && !isMainRouteTable(*rt) {
+			return nil, dependencyViolation
+		}
+	}
+	for _, acl := range r.networkACLs {
+		if aws.ToString(acl.VpcId) == vpcID && !aws.ToBool(acl.IsDefault) {
+			return nil, dependencyViolation
+		}
+	}
+	for _, sg := range r.securityGroups {
+		if aws.ToString(sg.VpcId) == vpcID && !isDefaultSecurityGroup(*sg) {
+			return nil, dependencyViolation
+		}
+	}
+	for _, nat := range r.natGateways {
+		if aws.ToString(nat.VpcId) == vpcID && nat.State != types.NatGatewayStateDeleted {
+			return nil, dependencyViolation
+		}
+	}
+	for _, endpoint := range r.vpcEndpoints {
+		if aws.ToString(endpoint.VpcId) == vpcID && endpoint.State != types.StateDeleted {
+			return nil, dependencyViolation
+		}
+	}
+	for _, eigw := range r.egressOnlyInternetGateways {
+		if eigwAttachedTo(eigw, vpcID) {
+			return nil, dependencyViolation
+		}
+	}
+	for _, owner := range r.vpcCidrBlocks {
+		if owner == vpcID {
+			return nil, dependencyViolation
+		}
+	}
+
+	// The main route table, default network ACL and default security group
+	// go with the VPC
+	for id, rt := range r.routeTables {
+		if aws.ToString(rt.VpcId) == vpcID {
+			delete(r.routeTables, id)
+		}
+	}
+	for id, acl := range r.networkACLs {
+		if aws.ToString(acl.VpcId) == vpcID {
+			delete(r.networkACLs, id)
+		}
+	}
+	for id, sg := range r.securityGroups {
+		if aws.ToString(sg.VpcId) == vpcID {
+			delete(r.securityGroups, id)
+		}
+	}
+	delete(r.vpcs, vpcID)
+	return &ec2.DeleteVpcOutput{}, nil
+}
+
+func (c *fakeEC2Client) CreateDefaultVpc(ctx context.Context, input *ec2.CreateDefaultVpcInput, optFns ...func(*ec2.Options)) (*ec2.CreateDefaultVpcOutput, error) {
+	r, err := c.begin("CreateDefaultVpc", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	for id, vpc := range r.vpcs {
+		if aws.ToBool(vpc.IsDefault) {
+			return nil, fakeError("DefaultVpcAlreadyExists", "A Default VPC already exists for this account in this region: %s", id)
+		}
+	}
+	vpcID := c.fake.addDefaultVPC(r)
+	return &ec2.CreateDefaultVpcOutput{Vpc: r.vpcs[vpcID]}, nil
+}
+
+func (c *fakeEC2Client) CreateDefaultSubnet(ctx context.Context, input *ec2.CreateDefaultSubnetInput, optFns ...func(*ec2.Options)) (*ec2.CreateDefaultSubnetOutput, error) {
+	r, err := c.begin("CreateDefaultSubnet", input.DryRun)
+	defer c.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	zone := aws.ToString(input.AvailabilityZone)
+	if !slices.Contains(r.zones, zone) {
+		return nil, fakeError("InvalidParameterValue", "Value (%s) for parameter availabilityZone is invalid", zone)
+	}
+	vpcID := ""
+	for id, vpc := range r.vpcs {
+		if aws.ToBool(vpc.IsDefault) {
+			vpcID = id
+		}
+	}
+	if vpcID == "" {
+		return nil, fakeError("DefaultVpcDoesNotExist", "No default VPC for this user")
+	}
+	for _, subnet := range r.subnets {
+		if aws.ToString(subnet.VpcId) == vpcID && aws.ToBool(subnet.DefaultForAz) && aws.ToString(subnet.AvailabilityZone) == zone {
+			return nil, fakeError("DefaultSubnetAlreadyExistsInAvailabilityZone", "%s already has a default subnet", zone)
+		}
+	}
+	subnetID := c.fake.addDefaultSubnet(r, vpcID, zone)
+	return &ec2.CreateDefaultSubnetOutput{Subnet: r.subnets[subnetID]}, nil
+}
+
+func discardSnapshot(snapshot RegionSnapshot) (string, error) {
+	return snapshot.Region + ".json", nil
+}
+
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
+
+func TestDeleteAllDefaultVPCs_fake(t *testing.T) {
+	fake := NewFakeEC2()
+	var regions []string
+	for i := range 16 {
+		region := fmt.Sprintf("test-region-%d", i)
+		fake.AddRegion(region, region+"a", region+"b", region+"c")
+		fake.AddDefaultVPC(region)
+		regions = append(regions, region)
+	}
+
+	// A custom VPC is left alone
+	custom := fake.AddVPC("test-region-0", "10.0.0.0/16")
+
+	// Extra route tables, network ACLs and security groups are cleaned up,
+	// including groups referred to by each other and by the default group
+	vpcID := fake.VPCs("test-region-1")[0]
+	subnet := fake.AddSubnet("test-region-1", vpcID, "test-region-1a", "172.31.128.0/24")
+	fake.AddRouteTable("test-region-1", vpcID, subnet)
+	fake.AddNetworkACL("test-region-1", vpcID, subnet)
+	web := fake.AddSecurityGroup("test-region-1", vpcID, "web")
+	db := fake.AddSecurityGroup("test-region-1", vpcID, "db")
+	fake.AddSecurityGroupReference("test-region-1", db, web)
+	fake.AddSecurityGroupReference("test-region-1", web, db)
+	fake.AddSecurityGroupReference("test-region-1", fake.DefaultSecurityGroup("test-region-1", vpcID), web)
+
+	// A running instance keeps a VPC in use
+	busyVPC := fake.VPCs("test-region-2")[0]
+	busySubnet := fake.AddSubnet("test-region-2", busyVPC, "test-region-2a", "172.31.200.0/24")
+	instanceID := fake.AddInstance("test-region-2", busySubnet)
+
+	result, err := DeleteAllDefaultVPCs(context.Background(), regions, fake.Client, Protection{}, CleanupOptions{}, discardSnapshot, 4)
+	if err != nil {
+		t.Fatalf("DeleteAllDefaultVPCs() error = %v", err)
+	}
+
+	for _, region := range result.Regions {
+		switch region.Region {
+		case "test-region-0":
+			if got := fake.VPCs(region.Region); !reflect.DeepEqual(got, []string{custom}) {
+				t.Errorf("%s VPCs = %v, want only the custom VPC %s", region.Region, got, custom)
+			}
+		case "test-region-2":
+			if want := "in use by instance " + instanceID; len(region.VPCs) != 1 || region.VPCs[0].SkipReason != want {
+				t.Errorf("%s VPCs = %+v, want skipped as %q", region.Region, region.VPCs, want)
+			}
+			if got := fake.Resources(region.Region, busyVPC); got == 0 {
+				t.Errorf("%s busy VPC was deleted", region.Region)
+			}
+		default:
+			if region.Failed() || len(region.VPCs) != 1 || !region.VPCs[0].Deleted() {
+				t.Errorf("%s = %+v, want its default VPC deleted", region.Region, region)
+			}
+			if got := fake.VPCs(region.Region); len(got) != 0 {
+				t.Errorf("%s VPCs left = %v, want none", region.Region, got)
+			}
+			if region.Region == "test-region-1" {
+				var revoked int
+				for _, r := range region.VPCs[0].Resources {
+					revoked += len(r.Rules)
+				}
+				if revoked != 3 {
+					t.Errorf("%s revoked %d rules, want 3", region.Region, revoked)
+				}
+			}
+		}
+	}
+}
+
+func TestFakeEC2_dependencies(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		setup    func(fake *FakeEC2, vpcID string)
+		call     func(client EC2API, vpcID string) error
+		wantCode string
+	}{
+		{
+			name: "subnet with an instance",
+			setup: func(fake *FakeEC2, vpcID string) {
+				fake.AddInstance("us-east-1", fake.Subnets("us-east-1", vpcID)[0])
+			},
+			call: func(client EC2API, vpcID string) error {
+				_, err := cleanupVPCResources(ctx, client, vpcID)
+				return err
+			},
+			wantCode: "DependencyViolation",
+		},
+		{
+			name: "VPC with an attached internet gateway",
+			call: func(client EC2API, vpcID string) error {
+				return deleteVPC(ctx, client, vpcID)
+			},
+			wantCode: "DependencyViolation",
+		},
+		{
+			name: "internet gateway with a mapped public address",
+			setup: func(fake *FakeEC2, vpcID string) {
+				eni := fake.AddNetworkInterface("us-east-1", fake.Subnets("us-east-1", vpcID)[0])
+				fake.AddPublicIP("us-east-1", eni)
+			},
+			call: func(client EC2API, vpcID string) error {
+				out, err := client.DescribeInternetGateways(ctx, &ec2.DescribeInternetGatewaysInput{})
+				if err != nil {
+					return err
+				}
+				_, err = client.DetachInternetGateway(ctx, &ec2.DetachInternetGatewayInput{
+					InternetGatewayId: out.InternetGateways[0].InternetGatewayId,
+					VpcId:             aws.String(vpcID),
+				})
+				return err
+			},
+			wantCode: "DependencyViolation",
+		},
+		{
+			name: "default security group",
+			call: func(client EC2API, vpcID string) error {
+				out, err := client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{})
+				if err != nil {
+					return err
+				}
+				_, err = client.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{GroupId: out.SecurityGroups[0].GroupId})
+				return err
+			},
+			wantCode: "CannotDelete",
+		},
+		{
+			name: "missing subnet",
+			call: func(client EC2API, vpcID string) error {
+				_, err := client.DeleteSubnet(ctx, &ec2.DeleteSubnetInput{SubnetId: aws.String("subnet-missing")})
+				return err
+			},
+			wantCode: "InvalidSubnetID.NotFound",
+		},
+		{
+			name: "dry run",
+			call: func(client EC2API, vpcID string) error {
+				_, err := client.DeleteVpc(ctx, &ec2.DeleteVpcInput{VpcId: aws.String(vpcID), DryRun: aws.Bool(true)})
+				return err
+			},
+			wantCode: "DryRunOperation",
+		},
+		{
+			name: "a second default VPC",
+			call: func(client EC2API, vpcID string) error {
+				_, err := client.CreateDefaultVpc(ctx, &ec2.CreateDefaultVpcInput{})
+				return err
+			},
+			wantCode: "DefaultVpcAlreadyExists",
+		},
+		{
+			name: "whole VPC with a NAT gateway and VPC endpoints",
+			setup: func(fake *FakeEC2, vpcID string) {
+				subnets := fake.Subnets("us-east-1", vpcID)
+				fake.AddNatGateway("us-east-1", subnets[0])
+				fake.AddVpcEndpoint("us-east-1", vpcID, subnets...)
+				fake.AddVpcEndpoint("us-east-1", vpcID)
+			},
+			call: func(client EC2API, vpcID string) error {
+				if _, err := cleanupVPCResources(ctx, client, vpcID); err != nil {
+					return err
+				}
+				return deleteVPC(ctx, client, vpcID)
+			},
+		},
+		{
+			name: "whole VPC with network interfaces left behind and a Lambda one",
+			setup: func(fake *FakeEC2, vpcID string) {
+				subnets := fake.Subnets("us-east-1", vpcID)
+				sg := fake.AddSecurityGroup("us-east-1", vpcID, "efs")
+				eni := fake.AddNetworkInterface("us-east-1", subnets[0], sg)
+				fake.AddPublicIP("us-east-1", eni)
+				fake.AddNetworkInterface("us-east-1", subnets[1])
+				fake.AddLambdaNetworkInterface("us-east-1", subnets[1])
+			},
+			call: func(client EC2API, vpcID string) error {
+				if _, err := cleanupVPCResources(ctx, client, vpcID); err != nil {
+					return err
+				}
+				return deleteVPC(ctx, client, vpcID)
+			},
+		},
+		{
+			name: "secondary CIDR block a subnet is in",
+			setup: func(fake *FakeEC2, vpcID string) {
+				fake.AddCidrBlock("us-east-1", vpcID, "100.64.0.0/16")
+				fake.AddSubnet("us-east-1", vpcID, "us-east-1a", "100.64.0.0/24")
+			},
+			call: func(client EC2API, vpcID string) error {
+				vpc, err := describeVPC(ctx, client, vpcID)
+				if err != nil {
+					return err
+				}
+				return disassociateVpcCidrBlock(ctx, client, vpcCidrAssociations(vpc)[0])
+			},
+			wantCode: "DependencyViolation",
+		},
+		{
+			name: "whole VPC with IPv6, an egress-only internet gateway and a secondary CIDR block",
+			setup: func(fake *FakeEC2, vpcID string) {
+				fake.AddCidrBlock("us-east-1", vpcID, "100.64.0.0/16")
+				fake.AddSubnet("us-east-1", vpcID, "us-east-1a", "100.64.0.0/24")
+				fake.AddIPv6CidrBlock("us-east-1", vpcID)
+				fake.AddEgressOnlyInternetGateway("us-east-1", vpcID)
+			},
+			call: func(client EC2API, vpcID string) error {
+				if _, err := cleanupVPCResources(ctx, client, vpcID); err != nil {
+					return err
+				}
+				return deleteVPC(ctx, client, vpcID)
+			},
+		},
+		{
+			name: "whole VPC",
+			call: func(client EC2API, vpcID string) error {
+				if _, err := cleanupVPCResources(ctx, client, vpcID); err != nil {
+					return err
+				}
+				return deleteVPC(ctx, client, vpcID)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeEC2()
+			fake.AddRegion("us-east-1", "us-east-1a", "us-east-1b")
+			vpcID := fake.AddDefaultVPC("us-east-1")
+			if tt.setup != nil {
+				tt.setup(fake, vpcID)
+			}
+
+			err := tt.call(fake.Client("us-east-1"), vpcID)
+			if got := errorCode(err); got != tt.wantCode {
+				t.Errorf("error = %v, want code %q", err, tt.wantCode)
+			}
+			if tt.wantCode == "" && fake.Resources("us-east-1", vpcID) != 0 {
+				t.Errorf("%d resources left in %s, want none", fake.Resources("us-east-1", vpcID), vpcID)
+			}
+			if tt.wantCode == "" && len(fake.Addresses("us-east-1")) != 0 {
+				t.Errorf("Elastic IPs left = %v, want them released", fake.Addresses("us-east-1"))
+			}
+		})
+	}
+}
+
+func TestFakeEC2_cidrBlocks(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeEC2()
+	fake.AddRegion("us-east-1", "us-east-1a")
+	vpcID := fake.AddVPC("us-east-1", "10.0.0.0/16")
+	secondaryID := fake.AddCidrBlock("us-east-1", vpcID, "100.64.0.0/16")
+	subnetID := fake.AddSubnet("us-east-1", vpcID, "us-east-1a", "10.0.0.0/24")
+	fake.AddIPv6CidrBlock("us-east-1", vpcID)
+	client := fake.Client("us-east-1")
+
+	vpcs, err := client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{VpcIds: []string{vpcID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	primaryID := vpcs.Vpcs[0].CidrBlockAssociationSet[0].AssociationId
+	ipv6ID := vpcs.Vpcs[0].Ipv6CidrBlockAssociationSet[0].AssociationId
+	subnets, err := client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{SubnetIds: []string{subnetID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subnetIPv6ID := subnets.Subnets[0].Ipv6CidrBlockAssociationSet[0].AssociationId
+
+	steps := []struct {
+		name     string
+		call     func() error
+		wantCode string
+	}{
+		{
+			name: "primary block",
+			call: func() error {
+				_, err := client.DisassociateVpcCidrBlock(ctx, &ec2.DisassociateVpcCidrBlockInput{AssociationId: primaryID})
+				return err
+			},
+			wantCode: "OperationNotPermitted",
+		},
+		{
+			name: "VPC IPv6 block a subnet still uses",
+			call: func() error {
+				_, err := client.DisassociateVpcCidrBlock(ctx, &ec2.DisassociateVpcCidrBlockInput{AssociationId: ipv6ID})
+				return err
+			},
+			wantCode: "DependencyViolation",
+		},
+		{
+			name: "subnet IPv6 block",
+			call: func() error {
+				_, err := client.DisassociateSubnetCidrBlock(ctx, &ec2.DisassociateSubnetCidrBlockInput{AssociationId: subnetIPv6ID})
+				return err
+			},
+		},
+		{
+			name: "subnet IPv6 block again",
+			call: func() error {
+				_, err := client.DisassociateSubnetCidrBlock(ctx, &ec2.DisassociateSubnetCidrBlockInput{AssociationId: subnetIPv6ID})
+				return err
+			},
+			wantCode: "InvalidSubnetCidrBlockAssociationID.NotFound",
+		},
+		{
+			name: "VPC IPv6 block",
+			call: func() error {
+				_, err := client.DisassociateVpcCidrBlock(ctx, &ec2.DisassociateVpcCidrBlockInput{AssociationId: ipv6ID})
+				return err
+			},
+		},
+		{
+			name: "subnet",
+			call: func() error {
+				_, err := client.DeleteSubnet(ctx, &ec2.DeleteSubnetInput{SubnetId: aws.String(subnetID)})
+				return err
+			},
+		},
+		{
+			name: "VPC with the secondary block",
+			call: func() error {
+				_, err := client.DeleteVpc(ctx, &ec2.DeleteVpcInput{VpcId: aws.String(vpcID)})
+				return err
+			},
+			wantCode: "DependencyViolation",
+		},
+		{
+			name: "secondary block",
+			call: func() error {
+				_, err := client.DisassociateVpcCidrBlock(ctx, &ec2.DisassociateVpcCidrBlockInput{AssociationId: aws.String(secondaryID)})
+				return err
+			},
+		},
+		{
+			name: "VPC",
+			call: func() error {
+				_, err := client.DeleteVpc(ctx, &ec2.DeleteVpcInput{VpcId: aws.String(vpcID)})
+				return err
+			},
+		},
+	}
+	for _, step := range steps {
+		if err := step.call(); errorCode(err) != step.wantCode {
+			t.Fatalf("%s: error = %v, want code %q", step.name, err, step.wantCode)
+		}
+	}
+}
+
+func TestFakeEC2_retry(t *testing.T) {
+	fake := NewFakeEC2()
+	fake.AddRegion("us-east-1", "us-east-1a")
+	vpcID := fake.AddDefaultVPC("us-east-1")
+	fake.FailNext("DeleteVpc", fakeError("DependencyViolation", "not yet"), fakeError("DependencyViolation", "not yet"))
+
+	policy := DefaultRetryPolicy()
+	policy.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	result, err := DeleteAllDefaultVPCs(context.Background(), []string{"us-east-1"}, withRetry(fake.Client, policy), Protection{}, CleanupOptions{}, discardSnapshot, 1)
+	if err != nil {
+		t.Fatalf("DeleteAllDefaultVPCs() error = %v", err)
+	}
+	if !result.Regions[0].VPCs[0].Deleted() {
+		t.Errorf("VPC %s was not deleted", vpcID)
+	}
+
+	attempts := 0
+	for _, call := range fake.Calls() {
+		if call == "us-east-1 DeleteVpc" {
+			attempts++
+		}
+	}
+	if attempts != 3 {
+		t.Errorf("DeleteVpc called %d times, want 3", attempts)
+	}
+}
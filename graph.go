@@ -0,0 +1,491 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// maxParallelSteps caps how many calls run at once against a single VPC. It
+// is a variable so tests can run steps one at a time.
+var maxParallelSteps = 4
+
+// vpcResources is everything that has to be removed from a VPC before it can
+// be deleted, as EC2 describes it
+type vpcResources struct {
+	Vpc              types.Vpc
+	InternetGateways []types.InternetGateway
+	Subnets          []types.Subnet
+	RouteTables      []types.RouteTable
+	NetworkACLs      []types.NetworkAcl
+	SecurityGroups   []types.SecurityGroup
+	NatGateways      []types.NatGateway
+	VpcEndpoints     []types.VpcEndpoint
+	Interfaces       []types.NetworkInterface
+
+	EgressOnlyInternetGateways []types.EgressOnlyInternetGateway
+}
+
+// Describe the resources in a VPC. The main route table and default network
+// ACL are left out as they go with the VPC. The default security group is
+// kept because its rules can refer to other groups, but it is never deleted.
+// The VPC itself is described for the CIDR blocks associated with it.
+func describeVPCResources(ctx context.Context, client EC2API, vpcID string) (vpcResources, error) {
+	var resources vpcResources
+	var err error
+
+	if resources.Vpc, err = describeVPC(ctx, client, vpcID); err != nil {
+		return resources, err
+	}
+	if resources.InternetGateways, err = describeInternetGateways(ctx, client, vpcID); err != nil {
+		return resources, err
+	}
+	if resources.Subnets, err = describeSubnets(ctx, client, vpcID); err != nil {
+		return resources, err
+	}
+
+	routeTables, err := describeRouteTables(ctx, client, vpcID)
+	if err != nil {
+		return resources, err
+	}
+	for _, rt := range routeTables {
+		if !isMainRouteTable(rt) {
+			resources.RouteTables = append(resources.RouteTables, rt)
+		}
+	}
+
+	acls, err := describeNetworkACLs(ctx, client, vpcID)
+	if err != nil {
+		return resources, err
+	}
+	for _, acl := range acls {
+		if !aws.ToBool(acl.IsDefault) {
+			resources.NetworkACLs = append(resources.NetworkACLs, acl)
+		}
+	}
+
+	if resources.SecurityGroups, err = describeSecurityGroups(ctx, client, vpcID); err != nil {
+		return resources, err
+	}
+	if resources.NatGateways, err = describeNatGateways(ctx, client, vpcID); err != nil {
+		return resources, err
+	}
+	if resources.VpcEndpoints, err = describeVpcEndpoints(ctx, client, vpcID); err != nil {
+		return resources, err
+	}
+	if resources.Interfaces, err = describeNetworkInterfaces(ctx, client, vpcFilter(vpcID)); err != nil {
+		return resources, err
+	}
+	if resources.EgressOnlyInternetGateways, err = describeEgressOnlyInternetGateways(ctx, client, vpcID); err != nil {
+		return resources, err
+	}
+	return resources, nil
+}
+
+// plan lists the IDs of the resources by type
+func (r vpcResources) plan(vpcID string) VPCPlan {
+	plan := VPCPlan{VpcID: vpcID}
+	for _, igw := range r.InternetGateways {
+		plan.InternetGateways = append(plan.InternetGateways, aws.ToString(igw.InternetGatewayId))
+	}
+	for _, subnet := range r.Subnets {
+		plan.Subnets = append(plan.Subnets, aws.ToString(subnet.SubnetId))
+	}
+	for _, rt := range r.RouteTables {
+		plan.RouteTables = append(plan.RouteTables, aws.ToString(rt.RouteTableId))
+	}
+	for _, acl := range r.NetworkACLs {
+		plan.NetworkACLs = append(plan.NetworkACLs, aws.ToString(acl.NetworkAclId))
+	}
+	for _, sg := range r.SecurityGroups {
+		if !isDefaultSecurityGroup(sg) {
+			plan.SecurityGroups = append(plan.SecurityGroups, aws.ToString(sg.GroupId))
+		}
+	}
+	for _, nat := range r.NatGateways {
+		plan.NatGateways = append(plan.NatGateways, aws.ToString(nat.NatGatewayId))
+		plan.ElasticIPs = append(plan.ElasticIPs, natGatewayAllocations(nat)...)
+	}
+	for _, endpoint := range r.VpcEndpoints {
+		plan.VpcEndpoints = append(plan.VpcEndpoints, aws.ToString(endpoint.VpcEndpointId))
+	}
+	for _, eni := range r.Interfaces {
+		plan.NetworkInterfaces = append(plan.NetworkInterfaces, aws.ToString(eni.NetworkInterfaceId))
+	}
+	for _, eigw := range r.EgressOnlyInternetGateways {
+		plan.EgressOnlyInternetGateways = append(plan.EgressOnlyInternetGateways, aws.ToString(eigw.EgressOnlyInternetGatewayId))
+	}
+	for _, subnet := range r.Subnets {
+		plan.CidrBlockAssociations = append(plan.CidrBlockAssociations, subnetIpv6Associations(subnet)...)
+	}
+	plan.CidrBlockAssociations = append(plan.CidrBlockAssociations, vpcCidrAssociations(r.Vpc)...)
+	return plan
+}
+
+// deleteGraph holds the calls that empty a VPC and the order they have to
+// happen in. after[i] lists the steps that must succeed before step i runs.
+type deleteGraph struct {
+	steps []PlanStep
+	after [][]int
+	index map[PlanStep]int
+}
+
+func (g *deleteGraph) add(step PlanStep) int {
+	if g.index == nil {
+		g.index = map[PlanStep]int{}
+	}
+	g.steps = append(g.steps, step)
+	g.after = append(g.after, nil)
+	g.index[step] = len(g.steps) - 1
+	return len(g.steps) - 1
+}
+
+// dependOn makes step wait for before. Steps that aren't in the graph are
+// ignored, as the resource has nothing to wait for.
+func (g *deleteGraph) dependOn(step, before PlanStep) {
+	i, ok := g.index[step]
+	j, found := g.index[before]
+	if ok && found && i != j && !slices.Contains(g.after[i], j) {
+		g.after[i] = append(g.after[i], j)
+	}
+}
+
+// newDeleteGraph works out which calls have to wait for which to empty a VPC.
+// Nothing starts until Lambda has removed the network interfaces it has
+// released, and network interfaces left behind are deleted before the subnets
+// and security groups they use and before the internet gateway is detached.
+// VPC endpoints and NAT gateways are deleted before the subnets, route tables
+// and security groups they use, and a NAT gateway before the internet gateway
+// is detached and its Elastic IPs are released. An internet gateway is
+// detached before it is deleted, a subnet is deleted before the route table
+// and network ACL it is associated with, and a security group is deleted once
+// its own references and every rule in the VPC referring to it have been
+// revoked, including rules on the default group. A subnet's IPv6 block is
+// disassociated just before the subnet is deleted, and the VPC's secondary
+// and IPv6 blocks once every subnet and egress-only internet gateway has
+// gone. A cycle left in the graph is an error.
+func newDeleteGraph(resources vpcResources) (*deleteGraph, error) {
+	g := &deleteGraph{}
+	deleteStep := func(resourceType string, id *string) PlanStep {
+		return PlanStep{Action: actionDelete, Type: resourceType, ID: aws.ToString(id)}
+	}
+
+	var waits []PlanStep
+	for _, eni := range resources.Interfaces {
+		switch {
+		case isReleasedLambdaENI(eni):
+			wait := PlanStep{Action: actionWait, Type: resourceNetworkInterface, ID: aws.ToString(eni.NetworkInterfaceId)}
+			g.add(wait)
+			waits = append(waits, wait)
+		case isOrphanedENI(eni):
+			g.add(deleteStep(resourceNetworkInterface, eni.NetworkInterfaceId))
+		}
+	}
+	for _, endpoint := range resources.VpcEndpoints {
+		g.add(deleteStep(resourceVpcEndpoint, endpoint.VpcEndpointId))
+	}
+	for _, nat := range resources.NatGateways {
+		g.add(deleteStep(resourceNatGateway, nat.NatGatewayId))
+		for _, allocationID := range natGatewayAllocations(nat) {
+			release := PlanStep{Action: actionRelease, Type: resourceElasticIP, ID: allocationID}
+			g.add(release)
+			g.dependOn(release, deleteStep(resourceNatGateway, nat.NatGatewayId))
+		}
+	}
+
+	for _, igw := range resources.InternetGateways {
+		detach := PlanStep{Action: actionDetach, Type: resourceInternetGateway, ID: aws.ToString(igw.InternetGatewayId)}
+		g.add(detach)
+		g.add(deleteStep(resourceInternetGateway, igw.InternetGatewayId))
+		g.dependOn(deleteStep(resourceInternetGateway, igw.InternetGatewayId), detach)
+	}
+	for _, eigw := range resources.EgressOnlyInternetGateways {
+		g.add(deleteStep(resourceEgressOnlyInternetGateway, eigw.EgressOnlyInternetGatewayId))
+	}
+	for _, subnet := range resources.Subnets {
+		for _, associationID := range subnetIpv6Associations(subnet) {
+			g.add(PlanStep{Action: actionDisassociate, Type: resourceSubnetCidrBlock, ID: associationID})
+		}
+		g.add(deleteStep(resourceSubnet, subnet.SubnetId))
+	}
+	for _, rt := range resources.RouteTables {
+		g.add(deleteStep(resourceRouteTable, rt.RouteTableId))
+		for _, association := range rt.Associations {
+			g.dependOn(deleteStep(resourceRouteTable, rt.RouteTableId), deleteStep(resourceSubnet, association.SubnetId))
+		}
+	}
+	for _, acl := range resources.NetworkACLs {
+		g.add(deleteStep(resourceNetworkACL, acl.NetworkAclId))
+		for _, association := range acl.Associations {
+			g.dependOn(deleteStep(resourceNetworkACL, acl.NetworkAclId), deleteStep(resourceSubnet, association.SubnetId))
+		}
+	}
+	groups := securityGroupIDs(resources.SecurityGroups)
+	for _, sg := range resources.SecurityGroups {
+		if len(referencedGroups(sg, groups)) > 0 {
+			g.add(PlanStep{Action: actionRevoke, Type: resourceSecurityGroup, ID: aws.ToString(sg.GroupId)})
+		}
+	}
+	for _, sg := range resources.SecurityGroups {
+		if !isDefaultSecurityGroup(sg) {
+			g.add(deleteStep(resourceSecurityGroup, sg.GroupId))
+		}
+	}
+	for _, sg := range resources.SecurityGroups {
+		revoke := PlanStep{Action: actionRevoke, Type: resourceSecurityGroup, ID: aws.ToString(sg.GroupId)}
+		g.dependOn(deleteStep(resourceSecurityGroup, sg.GroupId), revoke)
+		for _, referenced := range referencedGroups(sg, groups) {
+			g.dependOn(deleteStep(resourceSecurityGroup, aws.String(referenced)), revoke)
+		}
+	}
+
+	for _, endpoint := range resources.VpcEndpoints {
+		endpointDelete := deleteStep(resourceVpcEndpoint, endpoint.VpcEndpointId)
+		for _, subnetID := range endpoint.SubnetIds {
+			g.dependOn(deleteStep(resourceSubnet, aws.String(subnetID)), endpointDelete)
+		}
+		for _, rtID := range endpoint.RouteTableIds {
+			g.dependOn(deleteStep(resourceRouteTable, aws.String(rtID)), endpointDelete)
+		}
+		for _, group := range endpoint.Groups {
+			g.dependOn(deleteStep(resourceSecurityGroup, group.GroupId), endpointDelete)
+		}
+	}
+	for _, nat := range resources.NatGateways {
+		natDelete := deleteStep(resourceNatGateway, nat.NatGatewayId)
+		g.dependOn(deleteStep(resourceSubnet, nat.SubnetId), natDelete)
+		for _, igw := range resources.InternetGateways {
+			g.dependOn(PlanStep{Action: actionDetach, Type: resourceInternetGateway, ID: aws.ToString(igw.InternetGatewayId)}, natDelete)
+		}
+	}
+	for _, eni := range resources.Interfaces {
+		if !isOrphanedENI(eni) {
+			continue
+		}
+		eniDelete := deleteStep(resourceNetworkInterface, eni.NetworkInterfaceId)
+		g.dependOn(deleteStep(resourceSubnet, eni.SubnetId), eniDelete)
+		for _, group := range eni.Groups {
+			g.dependOn(deleteStep(resourceSecurityGroup, group.GroupId), eniDelete)
+		}
+		if eni.Association != nil && aws.ToString(eni.Association.PublicIp) != "" {
+			for _, igw := range resources.InternetGateways {
+				g.dependOn(PlanStep{Action: actionDetach, Type: resourceInternetGateway, ID: aws.ToString(igw.InternetGatewayId)}, eniDelete)
+			}
+		}
+	}
+	// A subnet's IPv6 block goes after whatever the subnet's delete waits
+	// for, as the interfaces using its addresses have to be gone first
+	for _, subnet := range resources.Subnets {
+		subnetDelete := g.index[deleteStep(resourceSubnet, subnet.SubnetId)]
+		for _, associationID := range subnetIpv6Associations(subnet) {
+			disassociate := PlanStep{Action: actionDisassociate, Type: resourceSubnetCidrBlock, ID: associationID}
+			for _, before := range g.after[subnetDelete] {
+				g.dependOn(disassociate, g.steps[before])
+			}
+			g.dependOn(g.steps[subnetDelete], disassociate)
+		}
+	}
+	for _, associationID := range vpcCidrAssociations(resources.Vpc) {
+		disassociate := PlanStep{Action: actionDisassociate, Type: resourceVpcCidrBlock, ID: associationID}
+		g.add(disassociate)
+		for _, subnet := range resources.Subnets {
+			g.dependOn(disassociate, deleteStep(resourceSubnet, subnet.SubnetId))
+		}
+		for _, eigw := range resources.EgressOnlyInternetGateways {
+			g.dependOn(disassociate, deleteStep(resourceEgressOnlyInternetGateway, eigw.EgressOnlyInternetGatewayId))
+		}
+	}
+	for _, step := range g.steps {
+		if step.Action == actionWait {
+			continue
+		}
+		for _, wait := range waits {
+			g.dependOn(step, wait)
+		}
+	}
+
+	if cycles := g.cycles(); len(cycles) > 0 {
+		return nil, g.cycleError(cycles[0])
+	}
+	return g, nil
+}
+
+// cycles finds every set of steps that wait on each other, using Tarjan's
+// strongly connected components. Each cycle is returned in graph order.
+func (g *deleteGraph) cycles() [][]int {
+	var (
+		next    int
+		order   = make([]int, len(g.steps))
+		low     = make([]int, len(g.steps))
+		onStack = make([]bool, len(g.steps))
+		stack   []int
+		cycles  [][]int
+	)
+	for i := range order {
+		order[i] = -1
+	}
+
+	var visit func(i int)
+	visit = func(i int) {
+		order[i], low[i] = next, next
+		next++
+		stack = append(stack, i)
+		onStack[i] = true
+		for _, j := range g.after[i] {
+			if order[j] < 0 {
+				visit(j)
+				low[i] = min(low[i], low[j])
+			} else if onStack[j] {
+				low[i] = min(low[i], order[j])
+			}
+		}
+		if low[i] != order[i] {
+			return
+		}
+		var component []int
+		for {
+			j := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[j] = false
+			component = append(component, j)
+			if j == i {
+				break
+			}
+		}
+		if len(component) > 1 {
+			slices.Sort(component)
+			cycles = append(cycles, component)
+		}
+	}
+	for i := range g.steps {
+		if order[i] < 0 {
+			visit(i)
+		}
+	}
+	return cycles
+}
+
+// cycleError names the steps in a cycle
+func (g *deleteGraph) cycleError(cycle []int) error {
+	var names []string
+	for _, i := range cycle {
+		names = append(names, g.steps[i].Action+" "+g.steps[i].Type+" "+g.steps[i].ID)
+	}
+	return fmt.Errorf("dependency cycle between %s", strings.Join(names, ", "))
+}
+
+// order sorts the steps so every step comes after the ones it waits for,
+// keeping graph order where it is free to
+func (g *deleteGraph) order() ([]PlanStep, error) {
+	waiting := make([]int, len(g.steps))
+	for i, after := range g.after {
+		waiting[i] = len(after)
+	}
+	done := make([]bool, len(g.steps))
+	var steps []PlanStep
+	for len(steps) < len(g.steps) {
+		next := -1
+		for i := range g.steps {
+			if !done[i] && waiting[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, errors.New("dependency cycle left in the delete graph")
+		}
+		done[next] = true
+		steps = append(steps, g.steps[next])
+		for i, after := range g.after {
+			if slices.Contains(after, next) {
+				waiting[i]--
+			}
+		}
+	}
+	return steps, nil
+}
+
+// run makes every step in the graph, starting each one as soon as the steps
+// it waits for have succeeded and running at most maxParallelSteps at once.
+// Once a step fails nothing new is started. The results of the steps that
+// ran are returned in graph order.
+func (g *deleteGraph) run(ctx context.Context, client EC2API, vpcID string) ([]ResourceResult, error) {
+	waiting := make([]int, len(g.steps))
+	dependents := make([][]int, len(g.steps))
+	for i, after := range g.after {
+		waiting[i] = len(after)
+		for _, j := range after {
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		slots   = make(chan struct{}, maxParallelSteps)
+		ran     = make([]bool, len(g.steps))
+		results = make([]ResourceResult, len(g.steps))
+		stopped bool
+	)
+	// start runs step i in the background once a slot is free, unless a step
+	// has failed by then. The slot is only given up after the result is
+	// recorded, so the next step to take it sees the failure. The caller
+	// holds mu.
+	var start func(i int)
+	start = func(i int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			mu.Lock()
+			if stopped {
+				mu.Unlock()
+				return
+			}
+			mu.Unlock()
+			result := runStep(ctx, client, vpcID, g.steps[i])
+
+			mu.Lock()
+			defer mu.Unlock()
+			ran[i], results[i] = true, result
+			if result.Err != nil {
+				stopped = true
+			}
+			if stopped {
+				return
+			}
+			for _, j := range dependents[i] {
+				if waiting[j]--; waiting[j] == 0 {
+					start(j)
+				}
+			}
+		}()
+	}
+
+	mu.Lock()
+	for i := range g.steps {
+		if waiting[i] == 0 {
+			start(i)
+		}
+	}
+	mu.Unlock()
+	wg.Wait()
+
+	var done []ResourceResult
+	var errs []error
+	for i := range g.steps {
+		if ran[i] {
+			done = append(done, results[i])
+			errs = append(errs, results[i].Err)
+		}
+	}
+	return done, errors.Join(errs...)
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// groupRule is an ingress rule allowing traffic from groupID
+func groupRule(groupID string) types.IpPermission {
+	return types.IpPermission{IpProtocol: aws.String("-1"), UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: aws.String(groupID)}}}
+}
+
+func Test_newDeleteGraph(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources vpcResources
+		want      []PlanStep
+	}{
+		{
+			name: "route table and network ACL wait for their subnets",
+			resources: vpcResources{
+				InternetGateways: []types.InternetGateway{{InternetGatewayId: aws.String("igw-1")}},
+				Subnets:          []types.Subnet{{SubnetId: aws.String("subnet-1")}, {SubnetId: aws.String("subnet-2")}},
+				RouteTables: []types.RouteTable{{
+					RouteTableId: aws.String("rtb-1"),
+					Associations: []types.RouteTableAssociation{{SubnetId: aws.String("subnet-2")}},
+				}},
+				NetworkACLs: []types.NetworkAcl{{
+					NetworkAclId: aws.String("acl-1"),
+					Associations: []types.NetworkAclAssociation{{SubnetId: aws.String("subnet-1")}},
+				}},
+			},
+			want: []PlanStep{
+				{Action: actionDetach, Type: resourceInternetGateway, ID: "igw-1"},
+				{Action: actionDelete, Type: resourceInternetGateway, ID: "igw-1"},
+				{Action: actionDelete, Type: resourceSubnet, ID: "subnet-1"},
+				{Action: actionDelete, Type: resourceSubnet, ID: "subnet-2"},
+				{Action: actionDelete, Type: resourceRouteTable, ID: "rtb-1"},
+				{Action: actionDelete, Type: resourceNetworkACL, ID: "acl-1"},
+			},
+		},
+		{
+			name: "references revoked before the referenced group is deleted",
+			resources: vpcResources{
+				SecurityGroups: []types.SecurityGroup{
+					{GroupId: aws.String("sg-web")},
+					{GroupId: aws.String("sg-db"), IpPermissions: []types.IpPermission{groupRule("sg-web"), groupRule("sg-db")}},
+				},
+			},
+			want: []PlanStep{
+				{Action: actionRevoke, Type: resourceSecurityGroup, ID: "sg-db"},
+				{Action: actionDelete, Type: resourceSecurityGroup, ID: "sg-web"},
+				{Action: actionDelete, Type: resourceSecurityGroup, ID: "sg-db"},
+			},
+		},
+		{
+			name: "groups referring to each other",
+			resources: vpcResources{
+				SecurityGroups: []types.SecurityGroup{
+					{GroupId: aws.String("sg-a"), IpPermissions: []types.IpPermission{groupRule("sg-b")}},
+					{GroupId: aws.String("sg-b"), IpPermissionsEgress: []types.IpPermission{groupRule("sg-a")}},
+					{GroupId: aws.String("sg-c"), IpPermissions: []types.IpPermission{groupRule("sg-a")}},
+				},
+			},
+			want: []PlanStep{
+				{Action: actionRevoke, Type: resourceSecurityGroup, ID: "sg-a"},
+				{Action: actionRevoke, Type: resourceSecurityGroup, ID: "sg-b"},
+				{Action: actionRevoke, Type: resourceSecurityGroup, ID: "sg-c"},
+				{Action: actionDelete, Type: resourceSecurityGroup, ID: "sg-a"},
+				{Action: actionDelete, Type: resourceSecurityGroup, ID: "sg-b"},
+				{Action: actionDelete, Type: resourceSecurityGroup, ID: "sg-c"},
+			},
+		},
+		{
+			name: "endpoint and NAT gateway go before what they use",
+			resources: vpcResources{
+				InternetGateways: []types.InternetGateway{{InternetGatewayId: aws.String("igw-1")}},
+				Subnets:          []types.Subnet{{SubnetId: aws.String("subnet-1")}},
+				SecurityGroups:   []types.SecurityGroup{{GroupId: aws.String("sg-1")}},
+				NatGateways: []types.NatGateway{{
+					NatGatewayId:        aws.String("nat-1"),
+					SubnetId:            aws.String("subnet-1"),
+					NatGatewayAddresses: []types.NatGatewayAddress{{AllocationId: aws.String("eipalloc-1")}},
+				}},
+				VpcEndpoints: []types.VpcEndpoint{{
+					VpcEndpointId: aws.String("vpce-1"),
+					SubnetIds:     []string{"subnet-1"},
+					Groups:        []types.SecurityGroupIdentifier{{GroupId: aws.String("sg-1")}},
+				}},
+			},
+			want: []PlanStep{
+				{Action: actionDelete, Type: resourceVpcEndpoint, ID: "vpce-1"},
+				{Action: actionDelete, Type: resourceNatGateway, ID: "nat-1"},
+				{Action: actionRelease, Type: resourceElasticIP, ID: "eipalloc-1"},
+				{Action: actionDetach, Type: resourceInternetGateway, ID: "igw-1"},
+				{Action: actionDelete, Type: resourceInternetGateway, ID: "igw-1"},
+				{Action: actionDelete, Type: resourceSubnet, ID: "subnet-1"},
+				{Action: actionDelete, Type: resourceSecurityGroup, ID: "sg-1"},
+			},
+		},
+		{
+			name: "Lambda waited out before anything else",
+			resources: vpcResources{
+				InternetGateways: []types.InternetGateway{{InternetGatewayId: aws.String("igw-1")}},
+				Subnets:          []types.Subnet{{SubnetId: aws.String("subnet-1")}},
+				SecurityGroups:   []types.SecurityGroup{{GroupId: aws.String("sg-1")}},
+				Interfaces: []types.NetworkInterface{
+					{
+						NetworkInterfaceId: aws.String("eni-1"),
+						SubnetId:           aws.String("subnet-1"),
+						Status:             types.NetworkInterfaceStatusAvailable,
+						Groups:             []types.GroupIdentifier{{GroupId: aws.String("sg-1")}},
+						Association:        &types.NetworkInterfaceAssociation{PublicIp: aws.String("203.0.113.10")},
+					},
+					{
+						NetworkInterfaceId: aws.String("eni-2"),
+						SubnetId:           aws.String("subnet-1"),
+						Status:             types.NetworkInterfaceStatusAvailable,
+						InterfaceType:      types.NetworkInterfaceTypeLambda,
+						RequesterManaged:   aws.Bool(true),
+					},
+					{
+						NetworkInterfaceId: aws.String("eni-3"),
+						SubnetId:           aws.String("subnet-1"),
+						Status:             types.NetworkInterfaceStatusAvailable,
+						RequesterManaged:   aws.Bool(true),
+					},
+					{
+						NetworkInterfaceId: aws.String("eni-4"),
+						SubnetId:           aws.String("subnet-1"),
+						Status:             types.NetworkInterfaceStatusInUse,
+						InterfaceType:      types.NetworkInterfaceTypeLambda,
+						RequesterManaged:   aws.Bool(true),
+					},
+				},
+			},
+			want: []PlanStep{
+				{Action: actionWait, Type: resourceNetworkInterface, ID: "eni-2"},
+				{Action: actionDelete, Type: resourceNetworkInterface, ID: "eni-1"},
+				{Action: actionDetach, Type: resourceInternetGateway, ID: "igw-1"},
+				{Action: actionDelete, Type: resourceInternetGateway, ID: "igw-1"},
+				{Action: actionDelete, Type: resourceSubnet, ID: "subnet-1"},
+				{Action: actionDelete, Type: resourceSecurityGroup, ID: "sg-1"},
+			},
+		},
+		{
+			name: "CIDR blocks disassociated around the subnets",
+			resources: vpcResources{
+				Vpc: types.Vpc{
+					CidrBlock: aws.String("172.31.0.0/16"),
+					CidrBlockAssociationSet: []types.VpcCidrBlockAssociation{
+						{AssociationId: aws.String("vpc-cidr-assoc-1"), CidrBlock: aws.String("172.31.0.0/16")},
+						{AssociationId: aws.String("vpc-cidr-assoc-2"), CidrBlock: aws.String("100.64.0.0/16")},
+					},
+					Ipv6CidrBlockAssociationSet: []types.VpcIpv6CidrBlockAssociation{{AssociationId: aws.String("vpc-cidr-assoc-3")}},
+				},
+				Subnets: []types.Subnet{{
+					SubnetId:                    aws.String("subnet-1"),
+					Ipv6CidrBlockAssociationSet: []types.SubnetIpv6CidrBlockAssociation{{AssociationId: aws.String("subnet-cidr-assoc-1")}},
+				}},
+				EgressOnlyInternetGateways: []types.EgressOnlyInternetGateway{{EgressOnlyInternetGatewayId: aws.String("eigw-1")}},
+				Interfaces: []types.NetworkInterface{{
+					NetworkInterfaceId: aws.String("eni-1"),
+					SubnetId:           aws.String("subnet-1"),
+					Status:             types.NetworkInterfaceStatusAvailable,
+				}},
+			},
+			want: []PlanStep{
+				{Action: actionDelete, Type: resourceNetworkInterface, ID: "eni-1"},
+				{Action: actionDelete, Type: resourceEgressOnlyInternetGateway, ID: "eigw-1"},
+				{Action: actionDisassociate, Type: resourceSubnetCidrBlock, ID: "subnet-cidr-assoc-1"},
+				{Action: actionDelete, Type: resourceSubnet, ID: "subnet-1"},
+				{Action: actionDisassociate, Type: resourceVpcCidrBlock, ID: "vpc-cidr-assoc-2"},
+				{Action: actionDisassociate, Type: resourceVpcCidrBlock, ID: "vpc-cidr-assoc-3"},
+			},
+		},
+		{
+			name: "default group revoked but kept",
+			resources: vpcResources{
+				SecurityGroups: []types.SecurityGroup{
+					{GroupId: aws.String("sg-default"), GroupName: aws.String("default"), IpPermissions: []types.IpPermission{groupRule("sg-web")}},
+					{GroupId: aws.String("sg-web")},
+				},
+			},
+			want: []PlanStep{
+				{Action: actionRevoke, Type: resourceSecurityGroup, ID: "sg-default"},
+				{Action: actionDelete, Type: resourceSecurityGroup, ID: "sg-web"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph, err := newDeleteGraph(tt.resources)
+			if err != nil {
+				t.Fatalf("newDeleteGraph() error = %v", err)
+			}
+			got, err := graph.order()
+			if err != nil {
+				t.Fatalf("order() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("order() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_deleteGraph_cycles(t *testing.T) {
+	g := &deleteGraph{}
+	subnet := PlanStep{Action: actionDelete, Type: resourceSubnet, ID: "subnet-1"}
+	rt := PlanStep{Action: actionDelete, Type: resourceRouteTable, ID: "rtb-1"}
+	g.add(subnet)
+	g.add(rt)
+	g.dependOn(subnet, rt)
+	g.dependOn(rt, subnet)
+
+	cycles := g.cycles()
+	if want := [][]int{{0, 1}}; !reflect.DeepEqual(cycles, want) {
+		t.Fatalf("cycles() = %v, want %v", cycles, want)
+	}
+	want := "dependency cycle between delete subnet subnet-1, delete route-table rtb-1"
+	if err := g.cycleError(cycles[0]); err == nil || err.Error() != want {
+		t.Errorf("cycleError() = %v, want %q", err, want)
+	}
+}
+
+func Test_deleteGraph_run(t *testing.T) {
+	fake := NewFakeEC2()
+	fake.AddRegion("us-east-1", "us-east-1a", "us-east-1b")
+	vpcID := fake.AddDefaultVPC("us-east-1")
+	subnets := fake.Subnets("us-east-1", vpcID)
+	fake.AddRouteTable("us-east-1", vpcID, subnets...)
+	fake.FailNext("DeleteSubnet", fakeError("InternalError", "try again"))
+	client := fake.Client("us-east-1")
+
+	resources, err := describeVPCResources(context.Background(), client, vpcID)
+	if err != nil {
+		t.Fatalf("describeVPCResources() error = %v", err)
+	}
+	graph, err := newDeleteGraph(resources)
+	if err != nil {
+		t.Fatalf("newDeleteGraph() error = %v", err)
+	}
+
+	results, err := graph.run(context.Background(), client, vpcID)
+	if err == nil {
+		t.Fatal("run() error = nil, want the failed subnet delete")
+	}
+	for _, result := range results {
+		if result.Type == resourceRouteTable {
+			t.Errorf("run() deleted route table %s after a subnet delete failed", result.ID)
+		}
+	}
+	if len(results) == 0 || len(results) == len(graph.steps) {
+		t.Errorf("run() made %d of %d steps, want it to stop part way", len(results), len(graph.steps))
+	}
+}
+
+func Test_deleteGraph_run_stopsQueuedSteps(t *testing.T) {
+	parallel := maxParallelSteps
+	maxParallelSteps = 1
+	defer func() { maxParallelSteps = parallel }()
+
+	var resources vpcResources
+	for i := 0; i < 9; i++ {
+		resources.Subnets = append(resources.Subnets, types.Subnet{SubnetId: aws.String(fmt.Sprintf("subnet-%d", i))})
+	}
+	graph, err := newDeleteGraph(resources)
+	if err != nil {
+		t.Fatalf("newDeleteGraph() error = %v", err)
+	}
+
+	var (
+		mu     sync.Mutex
+		failed bool
+		late   []string
+	)
+	client := &MockEC2Client{
+		deleteSubnetFunc: func(ctx context.Context, input *ec2.DeleteSubnetInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSubnetOutput, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if failed {
+				late = append(late, aws.ToString(input.SubnetId))
+			}
+			if aws.ToString(input.SubnetId) == "subnet-0" {
+				failed = true
+				return nil, fmt.Errorf("UnauthorizedOperation")
+			}
+			return &ec2.DeleteSubnetOutput{}, nil
+		},
+	}
+
+	if _, err := graph.run(context.Background(), client, "vpc-12345"); err == nil {
+		t.Fatal("run() error = nil, want the failed subnet delete")
+	}
+	if len(late) > 0 {
+		t.Errorf("run() deleted %v after subnet-0 failed, want nothing more", late)
+	}
+}
+
+func Test_revokeGroupReferences(t *testing.T) {
+	fake := NewFakeEC2()
+	fake.AddRegion("us-east-1", "us-east-1a")
+	vpcID := fake.AddDefaultVPC("us-east-1")
+	a := fake.AddSecurityGroup("us-east-1", vpcID, "a")
+	b := fake.AddSecurityGroup("us-east-1", vpcID, "b")
+	fake.AddSecurityGroupReference("us-east-1", a, b)
+	fake.AddSecurityGroupReference("us-east-1", a, a)
+	client := fake.Client("us-east-1")
+
+	rules, err := revokeGroupReferences(context.Background(), client, vpcID, a)
+	if err != nil {
+		t.Fatalf("revokeGroupReferences() error = %v", err)
+	}
+	if want := []string{"ingress all from " + b}; !reflect.DeepEqual(rules, want) {
+		t.Errorf("revokeGroupReferences() = %v, want %v", rules, want)
+	}
+	resources, err := describeVPCResources(context.Background(), client, vpcID)
+	if err != nil {
+		t.Fatalf("describeVPCResources() error = %v", err)
+	}
+	groups := securityGroupIDs(resources.SecurityGroups)
+	for _, sg := range resources.SecurityGroups {
+		if got := referencedGroups(sg, groups); len(got) != 0 {
+			t.Errorf("group %s still refers to %v", aws.ToString(sg.GroupId), got)
+		}
+		if aws.ToString(sg.GroupId) == a && len(sg.IpPermissions) != 1 {
+			t.Errorf("group %s rules = %v, want only its reference to itself kept", a, sg.IpPermissions)
+		}
+	}
+}
+
+func Test_revokeGroupReferences_describesNamedGroups(t *testing.T) {
+	groups := map[string]types.SecurityGroup{
+		"sg-a": {GroupId: aws.String("sg-a"), IpPermissions: []types.IpPermission{groupRule("sg-b"), groupRule("sg-peer")}},
+		"sg-b": {GroupId: aws.String("sg-b")},
+	}
+	var described [][]string
+	client := &MockEC2Client{
+		describeSecurityGroupsFunc: func(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+			out := &ec2.DescribeSecurityGroupsOutput{}
+			for _, filter := range input.Filters {
+				if aws.ToString(filter.Name) != "group-id" {
+					continue
+				}
+				described = append(described, filter.Values)
+				for _, id := range filter.Values {
+					if sg, ok := groups[id]; ok {
+						out.SecurityGroups = append(out.SecurityGroups, sg)
+					}
+				}
+			}
+			return out, nil
+		},
+		revokeSecurityGroupIngressFunc: func(ctx context.Context, input *ec2.RevokeSecurityGroupIngressInput, optFns ...func(*ec2.Options)) (*ec2.RevokeSecurityGroupIngressOutput, error) {
+			return &ec2.RevokeSecurityGroupIngressOutput{}, nil
+		},
+	}
+
+	rules, err := revokeGroupReferences(context.Background(), client, "vpc-12345", "sg-a")
+	if err != nil {
+		t.Fatalf("revokeGroupReferences() error = %v", err)
+	}
+	if want := []string{"ingress all from sg-b"}; !reflect.DeepEqual(rules, want) {
+		t.Errorf("revokeGroupReferences() = %v, want %v", rules, want)
+	}
+	if want := [][]string{{"sg-a"}, {"sg-b", "sg-peer"}}; !reflect.DeepEqual(described, want) {
+		t.Errorf("revokeGroupReferences() described groups %v, want %v", described, want)
+	}
+}
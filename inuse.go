@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+)
+
+func vpcFilter(vpcID string) types.Filter {
+	return types.Filter{
+		Name:   aws.String("vpc-id"),
+		Values: []string{vpcID},
+	}
+}
+
+// Describe the instances in a VPC that haven't been terminated
+func describeInstances(ctx context.Context, client EC2API, vpcID string) ([]types.Instance, error) {
+	var instances []types.Instance
+	paginator := ec2.NewDescribeInstancesPaginator(client, &ec2.DescribeInstancesInput{
+		Filters: []types.Filter{
+			vpcFilter(vpcID),
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: []string{"pending", "running", "shutting-down", "stopping", "stopped"},
+			},
+		},
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe instances: %w", err)
+		}
+		for _, reservation := range resp.Reservations {
+			instances = append(instances, reservation.Instances...)
+		}
+	}
+	return instances, nil
+}
+
+// Describe the NAT gateways in a VPC that are pending or available. Failed
+// gateways hold nothing and are removed by AWS, so they are left out.
+func describeNatGateways(ctx context.Context, client EC2API, vpcID string) ([]types.NatGateway, error) {
+	var natGateways []types.NatGateway
+	paginator := ec2.NewDescribeNatGatewaysPaginator(client, &ec2.DescribeNatGatewaysInput{
+		Filter: []types.Filter{
+			vpcFilter(vpcID),
+			{
+				Name:   aws.String("state"),
+				Values: []string{"pending", "available"},
+			},
+		},
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe NAT gateways: %w", err)
+		}
+		natGateways = append(natGateways, resp.NatGateways...)
+	}
+	return natGateways, nil
+}
+
+// Describe the VPC endpoints in a VPC that are not deleted
+func describeVpcEndpoints(ctx context.Context, client EC2API, vpcID string) ([]types.VpcEndpoint, error) {
+	var endpoints []types.VpcEndpoint
+	paginator := ec2.NewDescribeVpcEndpointsPaginator(client, &ec2.DescribeVpcEndpointsInput{
+		Filters: []types.Filter{vpcFilter(vpcID)},
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe VPC endpoints: %w", err)
+		}
+		for _, endpoint := range resp.VpcEndpoints {
+			switch endpoint.State {
+			case types.StateDeleted, types.StateDeleting:
+				continue
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints, nil
+}
+
+// Describe the application, network and gateway load balancers in a VPC.
+// ELBv2 can't filter by VPC, so every load balancer in the region is listed.
+func describeLoadBalancers(ctx context.Context, client EC2API, vpcID string) ([]elbv2types.LoadBalancer, error) {
+	var loadBalancers []elbv2types.LoadBalancer
+	paginator := elbv2.NewDescribeLoadBalancersPaginator(client, &elbv2.DescribeLoadBalancersInput{})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe load balancers: %w", err)
+		}
+		for _, lb := range resp.LoadBalancers {
+			if aws.ToString(lb.VpcId) == vpcID {
+				loadBalancers = append(loadBalancers, lb)
+			}
+		}
+	}
+	return loadBalancers, nil
+}
+
+// isELBv2ENI reports whether a network interface belongs to an application,
+// network or gateway load balancer, going by the "ELB app/...", "ELB net/..."
+// or "ELB gwy/..." description AWS gives it
+func isELBv2ENI(eni types.NetworkInterface) bool {
+	description := aws.ToString(eni.Description)
+	for _, prefix := range []string{"ELB app/", "ELB net/", "ELB gwy/"} {
+		if strings.HasPrefix(description, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe the network interfaces that match filter, such as every one in a
+// VPC or a subnet
+func describeNetworkInterfaces(ctx context.Context, client EC2API, filter types.Filter) ([]types.NetworkInterface, error) {
+	var enis []types.NetworkInterface
+	paginator := ec2.NewDescribeNetworkInterfacesPaginator(client, &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{filter},
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe network interfaces: %w", err)
+		}
+		enis = append(enis, resp.NetworkInterfaces...)
+	}
+	return enis, nil
+}
+
+// Describe what an in-use network interface belongs to. Interfaces owned by
+// instances, NAT gateways and VPC endpoints return "" because those are
+// reported on their own. Load balancers are recognised by the "ELB ..."
+// description AWS gives their interfaces.
+func describeENIOwner(eni types.NetworkInterface) string {
+	if eni.Status != types.NetworkInterfaceStatusInUse {
+		return ""
+	}
+	if eni.Attachment != nil && aws.ToString(eni.Attachment.InstanceId) != "" {
+		return ""
+	}
+	switch eni.InterfaceType {
+	case types.NetworkInterfaceTypeNatGateway, types.NetworkInterfaceTypeVpcEndpoint, types.NetworkInterfaceTypeGatewayLoadBalancerEndpoint:
+		return ""
+	}
+
+	description := aws.ToString(eni.Description)
+	if name, ok := strings.CutPrefix(description, "ELB "); ok {
+		return "load balancer " + name
+	}
+	if description == "" {
+		description = string(eni.InterfaceType)
+	}
+	return fmt.Sprintf("network interface %s (%s)", aws.ToString(eni.NetworkInterfaceId), description)
+}
+
+// CleanupOptions lists the resources that would otherwise keep a default VPC
+// in use but may be deleted along with it instead.
+type CleanupOptions struct {
+	DeleteNatGateways  bool
+	DeleteVpcEndpoints bool
+}
+
+// List the workloads still attached to a VPC: instances, NAT gateways, VPC
+// endpoints, load balancers and any other in-use network interfaces. NAT
+// gateways and VPC endpoints that cleanup allows to be deleted are left
+// out. Classic load balancers have no VPC filter of their own and are found
+// by their network interfaces. An empty result means nothing would break if
+// the VPC went away.
+func findVPCWorkloads(ctx context.Context, client EC2API, vpcID string, cleanup CleanupOptions) ([]string, error) {
+	var workloads []string
+
+	instances, err := describeInstances(ctx, client, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	for _, instance := range instances {
+		workloads = append(workloads, "instance "+aws.ToString(instance.InstanceId))
+	}
+
+	if !cleanup.DeleteNatGateways {
+		natGateways, err := describeNatGateways(ctx, client, vpcID)
+		if err != nil {
+			return nil, err
+		}
+		for _, nat := range natGateways {
+			workloads = append(workloads, "NAT gateway "+aws.ToString(nat.NatGatewayId))
+		}
+	}
+
+	if !cleanup.DeleteVpcEndpoints {
+		endpoints, err := describeVpcEndpoints(ctx, client, vpcID)
+		if err != nil {
+			return nil, err
+		}
+		for _, endpoint := range endpoints {
+			workloads = append(workloads, "VPC endpoint "+aws.ToString(endpoint.VpcEndpointId))
+		}
+	}
+
+	loadBalancers, err := describeLoadBalancers(ctx, client, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	for _, lb := range loadBalancers {
+		workloads = append(workloads, "load balancer "+aws.ToString(lb.LoadBalancerName))
+	}
+
+	enis, err := describeNetworkInterfaces(ctx, client, vpcFilter(vpcID))
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, eni := range enis {
+		if isELBv2ENI(eni) {
+			continue
+		}
+		owner := describeENIOwner(eni)
+		if owner == "" || seen[owner] {
+			continue
+		}
+		seen[owner] = true
+		workloads = append(workloads, owner)
+	}
+
+	return workloads, nil
+}
+
+// Explain why a VPC with workloads is left alone
+func inUseReason(workloads []string) string {
+	return "in use by " + strings.Join(workloads, ", ")
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+)
+
+func Test_describeENIOwner(t *testing.T) {
+	tests := []struct {
+		name string
+		eni  types.NetworkInterface
+		want string
+	}{
+		{
+			name: "load balancer",
+			eni: types.NetworkInterface{
+				NetworkInterfaceId: aws.String("eni-1"),
+				Status:             types.NetworkInterfaceStatusInUse,
+				Description:        aws.String("ELB app/web/0123456789abcdef"),
+			},
+			want: "load balancer app/web/0123456789abcdef",
+		},
+		{
+			name: "lambda",
+			eni: types.NetworkInterface{
+				NetworkInterfaceId: aws.String("eni-2"),
+				Status:             types.NetworkInterfaceStatusInUse,
+				InterfaceType:      types.NetworkInterfaceTypeLambda,
+			},
+			want: "network interface eni-2 (lambda)",
+		},
+		{
+			name: "instance attachment reported with the instance",
+			eni: types.NetworkInterface{
+				NetworkInterfaceId: aws.String("eni-3"),
+				Status:             types.NetworkInterfaceStatusInUse,
+				Attachment:         &types.NetworkInterfaceAttachment{InstanceId: aws.String("i-12345")},
+			},
+			want: "",
+		},
+		{
+			name: "NAT gateway reported with the gateway",
+			eni: types.NetworkInterface{
+				NetworkInterfaceId: aws.String("eni-4"),
+				Status:             types.NetworkInterfaceStatusInUse,
+				InterfaceType:      types.NetworkInterfaceTypeNatGateway,
+			},
+			want: "",
+		},
+		{
+			name: "available interface is not a workload",
+			eni: types.NetworkInterface{
+				NetworkInterfaceId: aws.String("eni-5"),
+				Status:             types.NetworkInterfaceStatusAvailable,
+			},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeENIOwner(tt.eni); got != tt.want {
+				t.Errorf("describeENIOwner() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_findVPCWorkloads(t *testing.T) {
+	emptyClient := func() *MockEC2Client {
+		return &MockEC2Client{
+			describeInstancesFunc: func(ctx context.Context, input *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+				return &ec2.DescribeInstancesOutput{}, nil
+			},
+			describeNatGatewaysFunc: func(ctx context.Context, input *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+				return &ec2.DescribeNatGatewaysOutput{}, nil
+			},
+			describeVpcEndpointsFunc: func(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+				return &ec2.DescribeVpcEndpointsOutput{}, nil
+			},
+			describeNetworkInterfacesFunc: func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+				return &ec2.DescribeNetworkInterfacesOutput{}, nil
+			},
+		}
+	}
+
+	busy := emptyClient()
+	busy.describeNatGatewaysFunc = func(ctx context.Context, input *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+		for _, filter := range input.Filter {
+			if aws.ToString(filter.Name) == "state" && slices.Contains(filter.Values, "failed") {
+				t.Errorf("DescribeNatGateways() state filter = %v, want failed gateways left out", filter.Values)
+			}
+		}
+		return &ec2.DescribeNatGatewaysOutput{NatGateways: []types.NatGateway{{NatGatewayId: aws.String("nat-1")}}}, nil
+	}
+	busy.describeVpcEndpointsFunc = func(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+		return &ec2.DescribeVpcEndpointsOutput{
+			VpcEndpoints: []types.VpcEndpoint{
+				{VpcEndpointId: aws.String("vpce-1"), State: types.StateAvailable},
+				{VpcEndpointId: aws.String("vpce-2"), State: types.StateDeleted},
+			},
+		}, nil
+	}
+	busy.describeNetworkInterfacesFunc = func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+		return &ec2.DescribeNetworkInterfacesOutput{
+			NetworkInterfaces: []types.NetworkInterface{
+				{NetworkInterfaceId: aws.String("eni-1"), Status: types.NetworkInterfaceStatusInUse, Description: aws.String("ELB net/api/1")},
+				{NetworkInterfaceId: aws.String("eni-2"), Status: types.NetworkInterfaceStatusInUse, Description: aws.String("ELB net/api/1")},
+				{NetworkInterfaceId: aws.String("eni-3"), Status: types.NetworkInterfaceStatusInUse, Description: aws.String("ELB classic-web")},
+			},
+		}, nil
+	}
+	busy.describeLoadBalancersFunc = func(ctx context.Context, input *elbv2.DescribeLoadBalancersInput, optFns ...func(*elbv2.Options)) (*elbv2.DescribeLoadBalancersOutput, error) {
+		return &elbv2.DescribeLoadBalancersOutput{
+			LoadBalancers: []elbv2types.LoadBalancer{
+				{LoadBalancerName: aws.String("api"), VpcId: aws.String("vpc-12345")},
+				{LoadBalancerName: aws.String("elsewhere"), VpcId: aws.String("vpc-67890")},
+			},
+		}, nil
+	}
+
+	failing := emptyClient()
+	failing.describeInstancesFunc = func(ctx context.Context, input *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+		return nil, fmt.Errorf("UnauthorizedOperation")
+	}
+
+	tests := []struct {
+		name    string
+		client  EC2API
+		cleanup CleanupOptions
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "unused VPC",
+			client: emptyClient(),
+			want:   nil,
+		},
+		{
+			name:   "NAT gateway, endpoint and load balancer",
+			client: busy,
+			want:   []string{"NAT gateway nat-1", "VPC endpoint vpce-1", "load balancer api", "load balancer classic-web"},
+		},
+		{
+			name:    "NAT gateway and endpoint to be deleted",
+			client:  busy,
+			cleanup: CleanupOptions{DeleteNatGateways: true, DeleteVpcEndpoints: true},
+			want:    []string{"load balancer api", "load balancer classic-web"},
+		},
+		{
+			name:    "error describing instances",
+			client:  failing,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findVPCWorkloads(context.Background(), tt.client, "vpc-12345", tt.cleanup)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("findVPCWorkloads() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findVPCWorkloads() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
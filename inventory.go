@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// RegionInventory lists the default VPCs found in a region. Protected ones
+// are kept apart from VPCs, which are the ones apply would delete.
+type RegionInventory struct {
+	Region    string
+	VPCs      []string
+	Protected []ProtectedVPC
+	Err       error
+}
+
+// Remaining is an error naming every unprotected default VPC still in the
+// region, or nil if there are none
+func (r RegionInventory) Remaining() error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if len(r.VPCs) > 0 {
+		return fmt.Errorf("region %s still has default VPCs: %v", r.Region, r.VPCs)
+	}
+	return nil
+}
+
+func inventoryRegion(ctx context.Context, client EC2API, region string, protection Protection) RegionInventory {
+	inventory := RegionInventory{Region: region}
+	vpcs, protected, err := getDefaultVPCs(ctx, client, protection)
+	if err != nil {
+		inventory.Err = fmt.Errorf("failed to list default VPCs in region %s: %w", region, err)
+		return inventory
+	}
+	inventory.VPCs = vpcs
+	inventory.Protected = protected
+	return inventory
+}
+
+// InventoryAllDefaultVPCs lists the default VPCs in every region, at most
+// concurrency regions at once
+func InventoryAllDefaultVPCs(ctx context.Context, regions []string, newClient ClientFactory, protection Protection, concurrency int) ([]RegionInventory, error) {
+	inventories := make([]RegionInventory, len(regions))
+
+	runBounded(len(regions), concurrency, func(i int) {
+		inventories[i] = inventoryRegion(ctx, newClient(regions[i]), regions[i], protection)
+	})
+
+	sort.Slice(inventories, func(i, j int) bool { return inventories[i].Region < inventories[j].Region })
+
+	var errs []error
+	for _, inventory := range inventories {
+		if inventory.Err != nil {
+			errs = append(errs, inventory.Err)
+		}
+	}
+	return inventories, errors.Join(errs...)
+}
+
+// InventoryAccounts lists the default VPCs in every account and region
+func InventoryAccounts(ctx context.Context, targets []accountTarget, protection Protection, concurrency int) [][]RegionInventory {
+	inventories := make([][]RegionInventory, len(targets))
+	for t, target := range targets {
+		inventories[t] = make([]RegionInventory, len(target.Regions.Regions))
+	}
+
+	forEachAccountRegion(ctx, targets, concurrency, func(ctx context.Context, t, r int) {
+		region := targets[t].Regions.Regions[r]
+		inventories[t][r] = inventoryRegion(ctx, targets[t].NewClient(region), region, protection)
+	})
+	return inventories
+}
+
+// verifyInventories joins an error for every region that still has an
+// unprotected default VPC or couldn't be checked
+func verifyInventories(inventories []RegionInventory) error {
+	var errs []error
+	for _, inventory := range inventories {
+		if err := inventory.Remaining(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Write the default VPCs found in each region
+func printInventory(w io.Writer, inventories []RegionInventory) {
+	for _, inventory := range inventories {
+		fmt.Fprintf(w, "Region %s\n", inventory.Region)
+		if inventory.Err != nil {
+			fmt.Fprintf(w, "  Error: %v\n", inventory.Err)
+			continue
+		}
+		if len(inventory.VPCs) == 0 && len(inventory.Protected) == 0 {
+			fmt.Fprintln(w, "  No default VPCs")
+			continue
+		}
+		for _, vpcID := range inventory.VPCs {
+			fmt.Fprintf(w, "  VPC %s\n", vpcID)
+		}
+		for _, protected := range inventory.Protected {
+			fmt.Fprintf(w, "  VPC %s: protected, %s\n", protected.VpcID, protected.Reason)
+		}
+	}
+}
+
+// Write whether each region is free of unprotected default VPCs
+func printVerify(w io.Writer, inventories []RegionInventory) {
+	for _, inventory := range inventories {
+		switch {
+		case inventory.Err != nil:
+			fmt.Fprintf(w, "Region %s: Error: %v\n", inventory.Region, inventory.Err)
+		case len(inventory.VPCs) > 0:
+			fmt.Fprintf(w, "Region %s: default VPCs remain: %v\n", inventory.Region, inventory.VPCs)
+		default:
+			fmt.Fprintf(w, "Region %s: no default VPCs\n", inventory.Region)
+		}
+		for _, protected := range inventory.Protected {
+			fmt.Fprintf(w, "  VPC %s kept: %s\n", protected.VpcID, protected.Reason)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestInventoryAllDefaultVPCs(t *testing.T) {
+	vpcsByRegion := map[string][]types.Vpc{
+		"eu-west-1": {
+			{VpcId: aws.String("vpc-eu"), IsDefault: aws.Bool(true)},
+			{VpcId: aws.String("vpc-custom"), IsDefault: aws.Bool(false)},
+		},
+		"us-east-1": {
+			{VpcId: aws.String("vpc-kept"), IsDefault: aws.Bool(true)},
+		},
+		"us-west-2": {},
+	}
+	newClient := func(region string) EC2API {
+		return &MockEC2Client{
+			describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+				if region == "ap-south-1" {
+					return nil, errors.New("access denied")
+				}
+				return &ec2.DescribeVpcsOutput{Vpcs: vpcsByRegion[region]}, nil
+			},
+		}
+	}
+	protection := Protection{VpcIDs: []string{"vpc-kept"}}
+
+	inventories, err := InventoryAllDefaultVPCs(context.Background(), []string{"us-west-2", "us-east-1", "eu-west-1", "ap-south-1"}, newClient, protection, 2)
+	if err == nil {
+		t.Fatal("InventoryAllDefaultVPCs() error = nil, want the ap-south-1 failure")
+	}
+
+	var regions []string
+	for _, inventory := range inventories {
+		regions = append(regions, inventory.Region)
+	}
+	if want := []string{"ap-south-1", "eu-west-1", "us-east-1", "us-west-2"}; !reflect.DeepEqual(regions, want) {
+		t.Fatalf("regions = %v, want %v", regions, want)
+	}
+	if got := inventories[1].VPCs; !reflect.DeepEqual(got, []string{"vpc-eu"}) {
+		t.Errorf("eu-west-1 VPCs = %v, want [vpc-eu]", got)
+	}
+	if got := inventories[2].Protected; len(got) != 1 || got[0].VpcID != "vpc-kept" {
+		t.Errorf("us-east-1 protected = %v, want vpc-kept", got)
+	}
+
+	tests := []struct {
+		name    string
+		region  int
+		wantErr bool
+	}{
+		{name: "describe failed", region: 0, wantErr: true},
+		{name: "default VPC left", region: 1, wantErr: true},
+		{name: "only protected VPCs left", region: 2},
+		{name: "no default VPCs", region: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyInventories([]RegionInventory{inventories[tt.region]})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyInventories() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// Log formats accepted by --log-format
+const (
+	logText = "text"
+	logJSON = "json"
+)
+
+// Attribute keys shared by every log record
+const (
+	logKeyAccount      = "account_id"
+	logKeyRegion       = "region"
+	logKeyVPC          = "vpc_id"
+	logKeyResourceType = "resource_type"
+	logKeyResourceID   = "resource_id"
+	logKeyError        = "error"
+)
+
+type loggerKey struct{}
+
+// withLogger returns a context that carries logger
+func withLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// loggerFrom returns the logger carried by ctx, or the default logger
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// withLogAttrs returns a context whose logger adds args to every record, so
+// records from concurrent regions can be told apart
+func withLogAttrs(ctx context.Context, args ...any) context.Context {
+	return withLogger(ctx, loggerFrom(ctx).With(args...))
+}
+
+// newLogger builds the logger for --log-format and --log-level
+func newLogger(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("--log-level must be debug, info, warn or error: %w", err)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch format {
+	case logText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case logJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("--log-format must be %s or %s", logText, logJSON)
+}
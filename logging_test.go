@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+func Test_newLogger(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		level   string
+		wantErr bool
+	}{
+		{name: "text", format: logText, level: "info"},
+		{name: "json at debug", format: logJSON, level: "debug"},
+		{name: "unknown format", format: "xml", level: "info", wantErr: true},
+		{name: "unknown level", format: logText, level: "loud", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newLogger(&bytes.Buffer{}, tt.format, tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newLogger() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_logAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, logJSON, "info")
+	if err != nil {
+		t.Fatalf("newLogger() error = %v", err)
+	}
+	ctx := withLogAttrs(withLogger(context.Background(), logger), logKeyRegion, "us-east-1", logKeyVPC, "vpc-12345")
+
+	client := &MockEC2Client{
+		deleteSubnetFunc: func(ctx context.Context, input *ec2.DeleteSubnetInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSubnetOutput, error) {
+			return &ec2.DeleteSubnetOutput{}, nil
+		},
+	}
+	if result := runStep(ctx, client, "vpc-12345", PlanStep{Action: actionDelete, Type: resourceSubnet, ID: "subnet-12345"}); result.Err != nil {
+		t.Fatalf("runStep() error = %v", result.Err)
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("log record is not JSON: %v: %s", err, buf.String())
+	}
+	want := map[string]string{
+		logKeyRegion:       "us-east-1",
+		logKeyVPC:          "vpc-12345",
+		logKeyResourceType: resourceSubnet,
+		logKeyResourceID:   "subnet-12345",
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("log record %s = %v, want %v", key, record[key], value)
+		}
+	}
+}
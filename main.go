@@ -2,57 +2,79 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"sync"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/smithy-go/middleware"
 )
 
-func getRegions(ctx context.Context, client EC2API) ([]string, error) {
-	resp, err := client.DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+// getRegions lists the account's regions. Without allRegions EC2 only
+// returns the regions that are enabled for the account.
+func getRegions(ctx context.Context, client EC2API, allRegions bool) ([]Region, error) {
+	resp, err := client.DescribeRegions(ctx, &ec2.DescribeRegionsInput{
+		AllRegions: aws.Bool(allRegions),
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	if len(resp.Regions) == 0 {
-		return []string{}, nil
+		return []Region{}, nil
 	}
 
-	regions := []string{}
+	regions := []Region{}
 	for _, region := range resp.Regions {
-		regions = append(regions, *region.RegionName)
+		regions = append(regions, Region{
+			Name:        aws.ToString(region.RegionName),
+			OptInStatus: aws.ToString(region.OptInStatus),
+		})
 	}
 
 	return regions, nil
 }
 
-func getDefaultVPCs(ctx context.Context, client EC2API) ([]string, error) {
-	resp, err := client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{})
-	if err != nil {
-		return nil, err
-	}
-
-	if len(resp.Vpcs) == 0 {
-		return []string{}, nil
-	}
-
+// getDefaultVPCs lists the region's default VPCs, setting aside the ones
+// protection matches by ID or tag
+func getDefaultVPCs(ctx context.Context, client EC2API, protection Protection) ([]string, []ProtectedVPC, error) {
 	vpcs := []string{}
-	for _, vpc := range resp.Vpcs {
-		if *vpc.IsDefault {
-			vpcs = append(vpcs, *vpc.VpcId)
+	var protected []ProtectedVPC
+	paginator := ec2.NewDescribeVpcsPaginator(client, &ec2.DescribeVpcsInput{})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, vpc := range resp.Vpcs {
+			if !aws.ToBool(vpc.IsDefault) {
+				continue
+			}
+			if reason := protection.reason(vpc); reason != "" {
+				protected = append(protected, ProtectedVPC{VpcID: aws.ToString(vpc.VpcId), Reason: reason})
+				continue
+			}
+			vpcs = append(vpcs, aws.ToString(vpc.VpcId))
 		}
 	}
 
-	return vpcs, nil
+	return vpcs, protected, nil
 }
 
-// Delete subnets in a VPC
-func deleteSubnets(ctx context.Context, client EC2API, vpcID string) error {
-	resp, err := client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
+// Describe subnets in a VPC
+func describeSubnets(ctx context.Context, client EC2API, vpcID string) ([]types.Subnet, error) {
+	var subnets []types.Subnet
+	paginator := ec2.NewDescribeSubnetsPaginator(client, &ec2.DescribeSubnetsInput{
 		Filters: []types.Filter{
 			{
 				Name:   aws.String("vpc-id"),
@@ -60,20 +82,14 @@ func deleteSubnets(ctx context.Context, client EC2API, vpcID string) error {
 			},
 		},
 	})
-	if err != nil {
-		return err
-	}
-
-	for _, subnet := range resp.Subnets {
-		_, err := client.DeleteSubnet(ctx, &ec2.DeleteSubnetInput{
-			SubnetId: subnet.SubnetId,
-		})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to delete subnet %s: %w", aws.ToString(subnet.SubnetId), err)
+			return nil, err
 		}
-		fmt.Printf("Deleted subnet: %s\n", aws.ToString(subnet.SubnetId))
+		subnets = append(subnets, resp.Subnets...)
 	}
-	return nil
+	return subnets, nil
 }
 
 func isMainRouteTable(rt types.RouteTable) bool {
@@ -85,9 +101,10 @@ func isMainRouteTable(rt types.RouteTable) bool {
 	return false
 }
 
-// Delete route tables in a VPC
-func deleteRouteTables(ctx context.Context, client EC2API, vpcID string) error {
-	resp, err := client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+// Describe route tables in a VPC
+func describeRouteTables(ctx context.Context, client EC2API, vpcID string) ([]types.RouteTable, error) {
+	var routeTables []types.RouteTable
+	paginator := ec2.NewDescribeRouteTablesPaginator(client, &ec2.DescribeRouteTablesInput{
 		Filters: []types.Filter{
 			{
 				Name:   aws.String("vpc-id"),
@@ -95,29 +112,20 @@ func deleteRouteTables(ctx context.Context, client EC2API, vpcID string) error {
 			},
 		},
 	})
-	if err != nil {
-		return fmt.Errorf("failed to describe route tables: %w", err)
-	}
-
-	for _, rt := range resp.RouteTables {
-		if isMainRouteTable(rt) {
-			continue
-		}
-
-		_, err := client.DeleteRouteTable(ctx, &ec2.DeleteRouteTableInput{
-			RouteTableId: rt.RouteTableId,
-		})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to delete route table %s: %w", aws.ToString(rt.RouteTableId), err)
+			return nil, fmt.Errorf("failed to describe route tables: %w", err)
 		}
-		fmt.Printf("Deleted route table: %s\n", aws.ToString(rt.RouteTableId))
+		routeTables = append(routeTables, resp.RouteTables...)
 	}
-	return nil
+	return routeTables, nil
 }
 
-// Detach and delete internet gateways in a VPC
-func deleteInternetGateways(ctx context.Context, client EC2API, vpcID string) error {
-	resp, err := client.DescribeInternetGateways(ctx, &ec2.DescribeInternetGatewaysInput{
+// Describe internet gateways attached to a VPC
+func describeInternetGateways(ctx context.Context, client EC2API, vpcID string) ([]types.InternetGateway, error) {
+	var igws []types.InternetGateway
+	paginator := ec2.NewDescribeInternetGatewaysPaginator(client, &ec2.DescribeInternetGatewaysInput{
 		Filters: []types.Filter{
 			{
 				Name:   aws.String("attachment.vpc-id"),
@@ -125,33 +133,20 @@ func deleteInternetGateways(ctx context.Context, client EC2API, vpcID string) er
 			},
 		},
 	})
-	if err != nil {
-		return err
-	}
-
-	for _, igw := range resp.InternetGateways {
-		_, err := client.DetachInternetGateway(ctx, &ec2.DetachInternetGatewayInput{
-			InternetGatewayId: igw.InternetGatewayId,
-			VpcId:             aws.String(vpcID),
-		})
-		if err != nil {
-			return fmt.Errorf("failed to detach internet gateway %s: %w", aws.ToString(igw.InternetGatewayId), err)
-		}
-
-		_, err = client.DeleteInternetGateway(ctx, &ec2.DeleteInternetGatewayInput{
-			InternetGatewayId: igw.InternetGatewayId,
-		})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to delete internet gateway %s: %w", aws.ToString(igw.InternetGatewayId), err)
+			return nil, err
 		}
-		fmt.Printf("Deleted internet gateway: %s\n", aws.ToString(igw.InternetGatewayId))
+		igws = append(igws, resp.InternetGateways...)
 	}
-	return nil
+	return igws, nil
 }
 
-// Delete security groups in a VPC
-func deleteSecurityGroups(ctx context.Context, client EC2API, vpcID string) error {
-	resp, err := client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+// Describe security groups in a VPC
+func describeSecurityGroups(ctx context.Context, client EC2API, vpcID string) ([]types.SecurityGroup, error) {
+	var sgs []types.SecurityGroup
+	paginator := ec2.NewDescribeSecurityGroupsPaginator(client, &ec2.DescribeSecurityGroupsInput{
 		Filters: []types.Filter{
 			{
 				Name:   aws.String("vpc-id"),
@@ -159,29 +154,25 @@ func deleteSecurityGroups(ctx context.Context, client EC2API, vpcID string) erro
 			},
 		},
 	})
-
-	if err != nil {
-		return err
-	}
-
-	for _, sg := range resp.SecurityGroups {
-		if *sg.GroupName == "default" {
-			continue
-		}
-		_, err := client.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{
-			GroupId: sg.GroupId,
-		})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to delete security group %s: %w", aws.ToString(sg.GroupId), err)
+			return nil, err
 		}
-		fmt.Printf("Deleted security group: %s\n", aws.ToString(sg.GroupId))
+		sgs = append(sgs, resp.SecurityGroups...)
 	}
-	return nil
+	return sgs, nil
+}
+
+// The default security group can't be deleted and goes away with the VPC
+func isDefaultSecurityGroup(sg types.SecurityGroup) bool {
+	return aws.ToString(sg.GroupName) == "default"
 }
 
-// Delete network ACLs in a VPC
-func deleteNetworkACLs(ctx context.Context, client EC2API, vpcID string) error {
-	resp, err := client.DescribeNetworkAcls(ctx, &ec2.DescribeNetworkAclsInput{
+// Describe network ACLs in a VPC
+func describeNetworkACLs(ctx context.Context, client EC2API, vpcID string) ([]types.NetworkAcl, error) {
+	var acls []types.NetworkAcl
+	paginator := ec2.NewDescribeNetworkAclsPaginator(client, &ec2.DescribeNetworkAclsInput{
 		Filters: []types.Filter{
 			{
 				Name:   aws.String("vpc-id"),
@@ -189,23 +180,14 @@ func deleteNetworkACLs(ctx context.Context, client EC2API, vpcID string) error {
 			},
 		},
 	})
-	if err != nil {
-		return err
-	}
-
-	for _, acl := range resp.NetworkAcls {
-		if *acl.IsDefault {
-			continue
-		}
-		_, err := client.DeleteNetworkAcl(ctx, &ec2.DeleteNetworkAclInput{
-			NetworkAclId: acl.NetworkAclId,
-		})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to delete network ACL %s: %w", aws.ToString(acl.NetworkAclId), err)
+			return nil, err
 		}
-		fmt.Printf("Deleted network ACL: %s\n", aws.ToString(acl.NetworkAclId))
+		acls = append(acls, resp.NetworkAcls...)
 	}
-	return nil
+	return acls, nil
 }
 
 // Delete the VPC after cleaning up resources
@@ -217,93 +199,454 @@ func deleteVPC(ctx context.Context, client EC2API, vpcID string) error {
 		return fmt.Errorf("failed to delete VPC %s: %w", vpcID, err)
 	}
 
-	fmt.Printf("Deleted VPC: %s\n", vpcID)
+	loggerFrom(ctx).Info("deleted", logKeyResourceType, resourceVPC, logKeyResourceID, vpcID)
 	return nil
 }
 
-// Clean up resources in a VPC before deleting it
-func cleanupVPCResources(ctx context.Context, client EC2API, vpcID string) error {
-	err := deleteInternetGateways(ctx, client, vpcID)
+// Clean up resources in a VPC before deleting it. The calls are ordered by
+// the VPC's delete graph and independent ones are made in parallel.
+func cleanupVPCResources(ctx context.Context, client EC2API, vpcID string) ([]ResourceResult, error) {
+	resources, err := describeVPCResources(ctx, client, vpcID)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	graph, err := newDeleteGraph(resources)
+	if err != nil {
+		return nil, err
+	}
+	return graph.run(ctx, client, vpcID)
+}
+
+// ClientFactory returns an EC2API bound to a region
+type ClientFactory func(region string) EC2API
+
+// NewClientFactory returns a ClientFactory backed by the real EC2 client.
+// apiOptions are added to every client's middleware, such as a shared
+// RateLimiter.
+func NewClientFactory(cfg aws.Config, apiOptions ...func(*middleware.Stack) error) ClientFactory {
+	return func(region string) EC2API {
+		return newRegionClient(cfg, region, apiOptions...)
+	}
+}
+
+// newRegionClient returns an EC2 client bound to the given region
+func newRegionClient(cfg aws.Config, region string, apiOptions ...func(*middleware.Stack) error) *EC2Client {
+	regionCfg := cfg.Copy()
+	regionCfg.Region = region
+	regionCfg.APIOptions = append(slices.Clone(regionCfg.APIOptions), apiOptions...)
+	return &EC2Client{Client: ec2.NewFromConfig(regionCfg), ELB: elbv2.NewFromConfig(regionCfg)}
+}
 
-	err = deleteSubnets(ctx, client, vpcID)
+// Clean up and delete a single default VPC
+func deleteDefaultVPC(ctx context.Context, client EC2API, region, vpcID string) VPCResult {
+	ctx = withLogAttrs(ctx, logKeyVPC, vpcID)
+	vpcResult := VPCResult{VpcID: vpcID}
+
+	resources, err := cleanupVPCResources(ctx, client, vpcID)
+	vpcResult.Resources = resources
 	if err != nil {
-		return err
+		vpcResult.Err = fmt.Errorf("failed to clean up resources for VPC %s in region %s: %w", vpcID, region, err)
+		loggerFrom(ctx).Error("failed to clean up VPC resources", logKeyError, err)
+		return vpcResult
 	}
 
-	err = deleteRouteTables(ctx, client, vpcID)
+	loggerFrom(ctx).Info("deleting default VPC")
+	err = deleteVPC(ctx, client, vpcID)
+	vpcResult.Resources = append(vpcResult.Resources, newResourceResult(resourceVPC, vpcID, actionDelete, err))
 	if err != nil {
-		return err
+		vpcResult.Err = fmt.Errorf("region %s: %w", region, err)
+		loggerFrom(ctx).Error("failed to delete VPC", logKeyError, err)
 	}
+	return vpcResult
+}
+
+// Delete every default VPC in a region. The VPCs that will be deleted are
+// snapshotted with backup first, and nothing is deleted if that fails. A
+// failure in one VPC is recorded and the remaining VPCs are still attempted.
+func deleteRegionDefaultVPCs(ctx context.Context, client EC2API, region string, protection Protection, cleanup CleanupOptions, backup SnapshotWriter) RegionResult {
+	ctx = withLogAttrs(ctx, logKeyRegion, region)
+	loggerFrom(ctx).Info("processing region")
+	result := RegionResult{Region: region}
 
-	err = deleteNetworkACLs(ctx, client, vpcID)
+	vpcs, protected, err := getDefaultVPCs(ctx, client, protection)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to fetch default VPCs in region %s: %w", region, err)
+		loggerFrom(ctx).Error("failed to fetch default VPCs", logKeyError, err)
+		return result
+	}
+	for _, p := range protected {
+		loggerFrom(ctx).Info("skipping protected default VPC", logKeyVPC, p.VpcID, "reason", p.Reason)
+		result.VPCs = append(result.VPCs, VPCResult{VpcID: p.VpcID, SkipReason: p.Reason, Protected: true})
+	}
+
+	var deletable []string
+	for _, vpcID := range vpcs {
+		workloads, err := findVPCWorkloads(ctx, client, vpcID, cleanup)
+		if err != nil {
+			result.VPCs = append(result.VPCs, VPCResult{
+				VpcID: vpcID,
+				Err:   fmt.Errorf("failed to check whether VPC %s in region %s is in use: %w", vpcID, region, err),
+			})
+			loggerFrom(ctx).Error("failed to check VPC for workloads", logKeyVPC, vpcID, logKeyError, err)
+			continue
+		}
+		if len(workloads) > 0 {
+			vpcResult := VPCResult{VpcID: vpcID, SkipReason: inUseReason(workloads)}
+			loggerFrom(ctx).Warn("skipping default VPC", logKeyVPC, vpcID, "reason", vpcResult.SkipReason)
+			result.VPCs = append(result.VPCs, vpcResult)
+			continue
+		}
+		deletable = append(deletable, vpcID)
+	}
+	if len(deletable) == 0 {
+		return result
+	}
+
+	snapshot := RegionSnapshot{Region: region}
+	for _, vpcID := range deletable {
+		vpcSnapshot, err := snapshotVPC(ctx, client, vpcID)
+		if err != nil {
+			result.Err = fmt.Errorf("failed to snapshot VPC %s in region %s, nothing was deleted: %w", vpcID, region, err)
+			loggerFrom(ctx).Error("failed to take snapshot", logKeyVPC, vpcID, logKeyError, err)
+			return result
+		}
+		snapshot.VPCs = append(snapshot.VPCs, vpcSnapshot)
+	}
+	result.SnapshotPath, err = backup(snapshot)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to save snapshot for region %s, nothing was deleted: %w", region, err)
+		loggerFrom(ctx).Error("failed to save snapshot", logKeyError, err)
+		return result
+	}
+	loggerFrom(ctx).Info("saved snapshot", "path", result.SnapshotPath)
+
+	for _, vpcID := range deletable {
+		result.VPCs = append(result.VPCs, deleteDefaultVPC(ctx, client, region, vpcID))
+	}
+	return result
+}
+
+// DeleteAllDefaultVPCs deletes all default VPCs in all regions apart from
+// the ones protection matches, saving a snapshot of each region with backup
+// first. At most concurrency regions are processed at once. Every region
+// runs to completion independently; the returned error joins every failure.
+func DeleteAllDefaultVPCs(ctx context.Context, regions []string, newClient ClientFactory, protection Protection, cleanup CleanupOptions, backup SnapshotWriter, concurrency int) (*RunResult, error) {
+	result := &RunResult{Regions: make([]RegionResult, len(regions))}
+
+	runBounded(len(regions), concurrency, func(i int) {
+		result.Regions[i] = deleteRegionDefaultVPCs(ctx, newClient(regions[i]), regions[i], protection, cleanup, backup)
+	})
+
+	sort.Slice(result.Regions, func(i, j int) bool { return result.Regions[i].Region < result.Regions[j].Region })
+	return result, result.Err()
+}
+
+// Subcommands
+const (
+	cmdList    = "list"
+	cmdPlan    = "plan"
+	cmdApply   = "apply"
+	cmdVerify  = "verify"
+	cmdRestore = "restore"
+)
+
+// runOptions holds the settings for a run, taken from the command line.
+// Command is one of list, plan, apply or verify. PlanOut is where plan saves
+// its plan, and PlanFile a saved plan for apply to run instead of planning
+// afresh. Yes skips typing the account ID before apply deletes anything.
+// Out is where plans, summaries and prompts are written.
+type runOptions struct {
+	Command       string
+	Out           io.Writer
+	Yes           bool
+	PlanOut       string
+	PlanFile      string
+	SkipPreflight bool
+	Regions       RegionSelection
+	Concurrency   int
+	SnapshotDir   string
+	Report        string
+	ReportFile    string
+	Retry         RetryPolicy
+	RateLimiter   *RateLimiter
+	Protection    Protection
+	Cleanup       CleanupOptions
+	Organization  OrganizationOptions
+}
+
+// Process the account the tool's own credentials belong to, recording the
+// outcome in report
+func runAccount(ctx context.Context, cfg aws.Config, opts runOptions, report *Report) error {
+	newClient := withRetry(NewClientFactory(cfg, opts.RateLimiter.apiOption()), opts.Retry)
+
+	accountID, err := callerAccountID(ctx, cfg)
 	if err != nil {
 		return err
 	}
+	ctx = withLogAttrs(ctx, logKeyAccount, accountID)
 
-	err = deleteSecurityGroups(ctx, client, vpcID)
+	regions, err := selectRegions(ctx, newClient(cfg.Region), opts.Regions)
 	if err != nil {
 		return err
 	}
-	return nil
-}
+	printRegions(opts.Out, regions)
+	report.Accounts = []AccountReport{newAccountReport(Account{ID: accountID}, regions.Skipped, nil)}
+	accountReport := &report.Accounts[0]
+
+	switch opts.Command {
+	case cmdList:
+		inventories, err := InventoryAllDefaultVPCs(ctx, regions.Regions, newClient, opts.Protection, opts.Concurrency)
+		printInventory(opts.Out, inventories)
+		accountReport.addInventory(inventories, false)
+		return err
+	case cmdVerify:
+		inventories, _ := InventoryAllDefaultVPCs(ctx, regions.Regions, newClient, opts.Protection, opts.Concurrency)
+		printVerify(opts.Out, inventories)
+		accountReport.addInventory(inventories, true)
+		return verifyInventories(inventories)
+	case cmdPlan:
+		plans, err := PlanAllDefaultVPCs(ctx, regions.Regions, newClient, opts.Protection, opts.Cleanup, opts.Concurrency)
+		printPlan(opts.Out, plans)
+		accountReport.addPlans(plans)
+		if err != nil || opts.PlanOut == "" {
+			return err
+		}
+		return savePlan(opts.Out, opts.PlanOut, []PlannedAccount{newPlannedAccount(Account{ID: accountID}, plans)})
+	}
 
-// deleteAllDefaultVPCs deletes all default VPCs in all regions
-func DeleteAllDefaultVPCs(ctx context.Context, regions []string, cfg aws.Config) {
-	var wg sync.WaitGroup
-	for _, region := range regions {
-		wg.Add(1)
-		go func(region string) {
-			defer wg.Done()
-			fmt.Printf("Processing region: %s\n", region)
-			regionCfg := cfg.Copy()
-			regionCfg.Region = region
-			ec2Client := &EC2Client{Client: ec2.NewFromConfig(regionCfg)}
-
-			vpcs, err := getDefaultVPCs(ctx, ec2Client)
-			if err != nil {
-				fmt.Printf("Error fetching default VPCs in region %s: %v\n", region, err)
-				return
-			}
+	if !opts.SkipPreflight {
+		reports, err := PreflightAllDefaultVPCs(ctx, regions.Regions, newClient, opts.Protection, opts.Cleanup, opts.Concurrency)
+		printPreflight(opts.Out, reports)
+		if err != nil {
+			accountReport.Status = statusFailed
+			return fmt.Errorf("preflight failed, nothing was deleted: %w", err)
+		}
+	}
 
-			for _, vpcID := range vpcs {
-				err := cleanupVPCResources(ctx, ec2Client, vpcID)
-				if err != nil {
-					fmt.Printf("Error cleaning up resources for VPC %s: %v", vpcID, err)
-					os.Exit(1)
-				}
-
-				fmt.Printf("Deleting default VPC %s in region %s\n", vpcID, region)
-				err = deleteVPC(ctx, ec2Client, vpcID)
-				if err != nil {
-					fmt.Printf("Error deleting VPC %s in region %s: %v", vpcID, region, err)
-					os.Exit(1)
-				}
+	if !opts.Yes {
+		// A region that fails to list is kept with its Err set, and still
+		// asks for confirmation
+		inventories, _ := InventoryAllDefaultVPCs(ctx, regions.Regions, newClient, opts.Protection, opts.Concurrency)
+		if mayDeleteVPCs(inventories) {
+			account := Account{ID: accountID, Name: accountAlias(ctx, cfg)}
+			err := confirmApply(opts.Out, accountID, confirmDelete, func(w io.Writer) {
+				fmt.Fprintf(w, "Account %s\n", account)
+				printInventory(w, inventories)
+			})
+			if err != nil {
+				return err
 			}
-		}(region)
+		}
 	}
 
-	wg.Wait()
-	fmt.Println("All default VPCs deleted.")
+	backup := newSnapshotWriter(opts.SnapshotDir, accountID, time.Now())
+
+	result, err := DeleteAllDefaultVPCs(ctx, regions.Regions, newClient, opts.Protection, opts.Cleanup, backup, opts.Concurrency)
+	result.Skipped = regions.Skipped
+	printSummary(opts.Out, result)
+	accountReport.addResult(result)
+	return err
 }
 
-func main() {
+// runMain runs list, plan, apply or verify, which share the same flags and
+// config file
+func runMain(command string, args []string) {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.Usage = func() {
+		if command == cmdApply {
+			fmt.Fprintf(flags.Output(), "Usage: remove-all-default-vpc %s [flags] [plan.json]\n", command)
+		} else {
+			fmt.Fprintf(flags.Output(), "Usage: remove-all-default-vpc %s [flags]\n", command)
+		}
+		flags.PrintDefaults()
+	}
+	yes := flags.Bool("yes", false, "with apply, delete without asking for the account ID to be typed; required when not run from a terminal")
+	planOut := flags.String("out", "", "with plan, file to save the plan to for a later apply")
+	configPath := flags.String("config", "", "YAML file of run settings, flags given on the command line take precedence (default $"+configEnv+")")
+	includeRegions := flags.String("regions", "", "comma separated regions to process, globs such as eu-* are allowed (default all)")
+	excludeRegions := flags.String("exclude-regions", "", "comma separated regions to leave alone, globs such as eu-* are allowed")
+	allRegions := flags.Bool("all-regions", false, "also look up regions that are not enabled, reporting their opt-in status")
+	skipPreflight := flags.Bool("skip-preflight", false, "skip the DryRun permission checks made before deleting anything")
+	org := flags.Bool("org", false, "process every account in the AWS Organization by assuming a role in each")
+	orgOUs := flags.String("org-ous", "", "comma separated organizational unit IDs to limit --org to, including nested OUs")
+	orgAccounts := flags.String("accounts", "", "comma separated account IDs to limit --org to")
+	roleName := flags.String("role-name", "OrganizationAccountAccessRole", "role to assume in each account with --org")
+	externalID := flags.String("external-id", "", "external ID to pass when assuming the role")
+	sessionName := flags.String("session-name", "remove-all-default-vpc", "session name to use when assuming the role")
+	snapshotDir := flags.String("snapshot-dir", "snapshots", "directory to write a JSON snapshot of each region's default VPCs to before deleting them")
+	protectVPCs := flags.String("protect-vpc", "", "comma separated default VPC IDs that are never deleted")
+	protectTags := flags.String("protect-tag", "", "comma separated key=value tags; default VPCs with any of them are never deleted")
+	deleteNatGateways := flags.Bool("delete-nat-gateways", false, "delete NAT gateways in default VPCs and release their Elastic IPs instead of skipping those VPCs as in use")
+	deleteVpcEndpoints := flags.Bool("delete-vpc-endpoints", false, "delete VPC endpoints in default VPCs instead of skipping those VPCs as in use")
+	defaultRetry := DefaultRetryPolicy()
+	retryMaxAttempts := flags.Int("retry-max-attempts", defaultRetry.MaxAttempts, "attempts made at each Delete, Detach or Revoke call before giving up")
+	retryBaseDelay := flags.Duration("retry-base-delay", defaultRetry.BaseDelay, "delay before the first retry, doubled on each one after with random jitter")
+	retryMaxDelay := flags.Duration("retry-max-delay", defaultRetry.MaxDelay, "longest delay between retries")
+	retryCodes := flags.String("retry-codes", strings.Join(defaultRetry.Codes, ","), "comma separated EC2 error codes that are retried")
+	logFormat := flags.String("log-format", logText, "log format written to stderr: text or json")
+	logLevel := flags.String("log-level", "info", "lowest log level to write: debug, info, warn or error")
+	reportFormat := flags.String("report", reportText, "report format: text prints a summary, json also writes a machine readable report")
+	reportFile := flags.String("report-file", "-", "file to write the --report json document to, - for stdout, which moves the usual output to stderr")
+	concurrency := flags.Int("concurrency", 8, "maximum number of regions, or account and region pairs with --org, processed at once")
+	rateLimit := flags.Float64("rate-limit", 20, "average EC2 API requests per second across all regions and accounts, 0 for no limit")
+	rateBurst := flags.Int("rate-burst", 40, "EC2 API requests allowed in a burst above --rate-limit")
+	flags.Parse(args)
+
+	if *configPath == "" {
+		*configPath = os.Getenv(configEnv)
+	}
+	var configTags []types.Tag
+	if *configPath != "" {
+		var err error
+		if configTags, err = applyConfig(flags, *configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	opts := runOptions{
+		Command:       command,
+		Out:           os.Stdout,
+		Yes:           *yes,
+		PlanOut:       *planOut,
+		PlanFile:      flags.Arg(0),
+		SkipPreflight: *skipPreflight,
+		Regions: RegionSelection{
+			AllRegions: *allRegions,
+			Include:    splitList(*includeRegions),
+			Exclude:    splitList(*excludeRegions),
+		},
+		Concurrency: *concurrency,
+		RateLimiter: NewRateLimiter(*rateLimit, *rateBurst),
+		SnapshotDir: *snapshotDir,
+		Report:      *reportFormat,
+		ReportFile:  *reportFile,
+		Retry: RetryPolicy{
+			MaxAttempts: *retryMaxAttempts,
+			BaseDelay:   *retryBaseDelay,
+			MaxDelay:    *retryMaxDelay,
+			Codes:       splitList(*retryCodes),
+		},
+		Organization: OrganizationOptions{
+			Enabled:     *org,
+			OUs:         splitList(*orgOUs),
+			AccountIDs:  splitList(*orgAccounts),
+			RoleName:    *roleName,
+			ExternalID:  *externalID,
+			SessionName: *sessionName,
+		},
+	}
+	if flags.NArg() > 1 || (flags.NArg() == 1 && command != cmdApply) {
+		fmt.Fprintf(os.Stderr, "Invalid options: only apply takes an argument, the plan file, got %q\n", flags.Args())
+		os.Exit(2)
+	}
+	if opts.PlanOut != "" && command != cmdPlan {
+		fmt.Fprintln(os.Stderr, "Invalid options: --out only works with plan")
+		os.Exit(2)
+	}
+	if command == cmdApply && !opts.Yes && !isTerminal(os.Stdin) {
+		fmt.Fprintln(os.Stderr, "Invalid options: not running in a terminal, pass --yes to apply without typing the account ID")
+		os.Exit(2)
+	}
+	if opts.Concurrency < 1 {
+		fmt.Fprintln(os.Stderr, "Invalid options: --concurrency must be at least 1")
+		os.Exit(2)
+	}
+	if opts.Report != reportText && opts.Report != reportJSON {
+		fmt.Fprintf(os.Stderr, "Invalid options: --report must be %s or %s\n", reportText, reportJSON)
+		os.Exit(2)
+	}
+	if opts.Report == reportJSON && (opts.ReportFile == "" || opts.ReportFile == "-") {
+		opts.Out = os.Stderr
+	}
+	if err := opts.Organization.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		os.Exit(2)
+	}
+	if err := opts.Retry.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		os.Exit(2)
+	}
+	protectTagList, err := parseProtectTags(splitList(*protectTags))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		os.Exit(2)
+	}
+	if configTags != nil {
+		protectTagList = configTags
+	}
+	opts.Protection = Protection{VpcIDs: splitList(*protectVPCs), Tags: protectTagList}
+	opts.Cleanup = CleanupOptions{DeleteNatGateways: *deleteNatGateways, DeleteVpcEndpoints: *deleteVpcEndpoints}
+
+	logger, err := newLogger(os.Stderr, *logFormat, *logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
 	ctx := context.Background()
 	cfg, err := config.LoadDefaultConfig(ctx)
 	if err != nil {
-		fmt.Printf("Unable to load AWS SDK config: %v", err)
+		fmt.Fprintf(opts.Out, "Unable to load AWS SDK config: %v\n", err)
 		os.Exit(1)
 	}
 
-	ec2Client := &EC2Client{Client: ec2.NewFromConfig(cfg)}
-
-	regions, err := getRegions(ctx, ec2Client)
+	report := &Report{StartedAt: time.Now().UTC(), Command: command, DryRun: command != cmdApply}
+	switch {
+	case opts.PlanFile != "":
+		err = runPlanFile(ctx, cfg, opts, report)
+	case opts.Organization.Enabled:
+		err = runOrganization(ctx, cfg, opts, report)
+	default:
+		err = runAccount(ctx, cfg, opts, report)
+	}
+	report.finish(err)
+	report.APIRequests, report.APIRequestsPerSecond = opts.RateLimiter.Requests()
+	slog.Info("made EC2 API requests", "requests", report.APIRequests, "per_second", report.APIRequestsPerSecond)
+	if opts.Report == reportJSON {
+		if reportErr := writeReport(opts.ReportFile, report); reportErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", reportErr)
+			os.Exit(1)
+		}
+	}
 	if err != nil {
-		fmt.Printf("Unable to describe regions: %v", err)
+		fmt.Fprintf(opts.Out, "Error: %v\n", err)
 		os.Exit(1)
 	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, `Usage: remove-all-default-vpc <command> [flags]
+
+Commands:
+  list     list the default VPCs in each region
+  plan     show everything apply would delete, without deleting anything
+  apply    delete the default VPCs and everything in them
+  verify   check that no unprotected default VPCs remain
+  restore  recreate the default VPCs
+
+Run remove-all-default-vpc <command> -h for the command's flags.
+`)
+}
 
-	DeleteAllDefaultVPCs(ctx, regions, cfg)
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	command, args := os.Args[1], os.Args[2:]
+	switch command {
+	case cmdList, cmdPlan, cmdApply, cmdVerify:
+		runMain(command, args)
+	case cmdRestore:
+		restoreMain(args)
+	case "help", "-h", "-help", "--help":
+		usage(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", command)
+		usage(os.Stderr)
+		os.Exit(2)
+	}
 }
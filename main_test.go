@@ -2,9 +2,10 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"sync"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -21,7 +22,7 @@ func Test_getRegions(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		want    []string
+		want    []Region
 		wantErr bool
 	}{
 		{
@@ -33,17 +34,22 @@ func Test_getRegions(t *testing.T) {
 						return &ec2.DescribeRegionsOutput{
 							Regions: []types.Region{
 								{
-									RegionName: aws.String("us-east-1"),
+									RegionName:  aws.String("us-east-1"),
+									OptInStatus: aws.String("opt-in-not-required"),
 								},
 								{
-									RegionName: aws.String("us-west-2"),
+									RegionName:  aws.String("ap-east-1"),
+									OptInStatus: aws.String("not-opted-in"),
 								},
 							},
 						}, nil
 					},
 				},
 			},
-			want:    []string{"us-east-1", "us-west-2"},
+			want: []Region{
+				{Name: "us-east-1", OptInStatus: "opt-in-not-required"},
+				{Name: "ap-east-1", OptInStatus: "not-opted-in"},
+			},
 			wantErr: false,
 		},
 		{
@@ -71,759 +77,207 @@ func Test_getRegions(t *testing.T) {
 					},
 				},
 			},
-			want:    []string{},
+			want:    []Region{},
 			wantErr: false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := getRegions(tt.args.ctx, tt.args.client)
+			got, err := getRegions(tt.args.ctx, tt.args.client, true)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getRegions() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("getRegions() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func Test_getDefaultVPCs(t *testing.T) {
-	type args struct {
-		ctx    context.Context
-		client EC2API
-	}
-
-	tests := []struct {
-		name    string
-		args    args
-		want    []string
-		wantErr bool
-	}{
-		{
-			name: "success - multiple default VPCs",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
-						return &ec2.DescribeVpcsOutput{
-							Vpcs: []types.Vpc{
-								{VpcId: aws.String("vpc-12345"), IsDefault: aws.Bool(true)},
-								{VpcId: aws.String("vpc-67890"), IsDefault: aws.Bool(true)},
-							},
-						}, nil
-					},
-				},
-			},
-			want:    []string{"vpc-12345", "vpc-67890"},
-			wantErr: false,
-		},
-		{
-			name: "error fetching VPCs",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
-						return nil, fmt.Errorf("failed to fetch VPCs")
-					},
-				},
-			},
-			want:    nil,
-			wantErr: true,
-		},
-		{
-			name: "no default VPCs",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
-						return &ec2.DescribeVpcsOutput{
-							Vpcs: []types.Vpc{},
-						}, nil
-					},
-				},
-			},
-			want:    []string{},
-			wantErr: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := getDefaultVPCs(tt.args.ctx, tt.args.client)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("getDefaultVPCs() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("getDefaultVPCs() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func Test_deleteSubnets(t *testing.T) {
-	type args struct {
-		ctx    context.Context
-		client EC2API
-		vpcID  string
-	}
-
-	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
-	}{
-		{
-			name: "success - delete multiple subnets",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeSubnetsFunc: func(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
-						return &ec2.DescribeSubnetsOutput{
-							Subnets: []types.Subnet{
-								{SubnetId: aws.String("subnet-1")},
-								{SubnetId: aws.String("subnet-2")},
-							},
-						}, nil
-					},
-					deleteSubnetFunc: func(ctx context.Context, input *ec2.DeleteSubnetInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSubnetOutput, error) {
-						return &ec2.DeleteSubnetOutput{}, nil
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: false,
-		},
-		{
-			name: "error fetching subnets",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeSubnetsFunc: func(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
-						return nil, fmt.Errorf("failed to fetch subnets")
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: true,
-		},
-		{
-			name: "error deleting subnet",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeSubnetsFunc: func(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
-						return &ec2.DescribeSubnetsOutput{
-							Subnets: []types.Subnet{
-								{SubnetId: aws.String("subnet-1")},
-							},
-						}, nil
-					},
-					deleteSubnetFunc: func(ctx context.Context, input *ec2.DeleteSubnetInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSubnetOutput, error) {
-						return nil, fmt.Errorf("failed to delete subnet subnet-1")
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := deleteSubnets(tt.args.ctx, tt.args.client, tt.args.vpcID)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("deleteSubnets() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
-	}
-}
-
-func Test_isMainRouteTable(t *testing.T) {
-	type args struct {
-		rt types.RouteTable
-	}
-	tests := []struct {
-		name string
-		args args
-		want bool
-	}{
-		{
-			name: "main route table",
-			args: args{
-				rt: types.RouteTable{
-					Associations: []types.RouteTableAssociation{
-						{
-							Main: aws.Bool(true),
-						},
-					},
-				},
-			},
-			want: true,
-		},
-		{
-			name: "non-main route table",
-			args: args{
-				rt: types.RouteTable{
-					Associations: []types.RouteTableAssociation{
-						{
-							Main: aws.Bool(false),
-						},
-					},
-				},
-			},
-			want: false,
-		},
-		{
-			name: "empty associations",
-			args: args{
-				rt: types.RouteTable{
-					Associations: []types.RouteTableAssociation{},
-				},
-			},
-			want: false,
-		},
-		{
-			name: "nil main association",
-			args: args{
-				rt: types.RouteTable{
-					Associations: []types.RouteTableAssociation{
-						{
-							Main: nil,
-						},
-					},
-				},
-			},
-			want: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := isMainRouteTable(tt.args.rt); got != tt.want {
-				t.Errorf("isMainRouteTable() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func Test_deleteRouteTables(t *testing.T) {
-	type args struct {
-		ctx    context.Context
-		client EC2API
-		vpcID  string
-	}
-
-	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
-	}{
-		{
-			name: "success - delete multiple route tables",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeRouteTablesFunc: func(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
-						return &ec2.DescribeRouteTablesOutput{
-							RouteTables: []types.RouteTable{
-								{RouteTableId: aws.String("rtb-1")},
-								{RouteTableId: aws.String("rtb-2")},
-							},
-						}, nil
-					},
-					deleteRouteTableFunc: func(ctx context.Context, input *ec2.DeleteRouteTableInput, optFns ...func(*ec2.Options)) (*ec2.DeleteRouteTableOutput, error) {
-						return &ec2.DeleteRouteTableOutput{}, nil
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: false,
-		},
-		{
-			name: "error describing route tables",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeRouteTablesFunc: func(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
-						return nil, fmt.Errorf("failed to describe route tables")
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: true,
-		},
-		{
-			name: "error deleting route table",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeRouteTablesFunc: func(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
-						return &ec2.DescribeRouteTablesOutput{
-							RouteTables: []types.RouteTable{
-								{RouteTableId: aws.String("rtb-1")},
-							},
-						}, nil
-					},
-					deleteRouteTableFunc: func(ctx context.Context, input *ec2.DeleteRouteTableInput, optFns ...func(*ec2.Options)) (*ec2.DeleteRouteTableOutput, error) {
-						return nil, fmt.Errorf("failed to delete route table")
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: true,
-		},
-		{
-			name: "no route tables found",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeRouteTablesFunc: func(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
-						return &ec2.DescribeRouteTablesOutput{
-							RouteTables: []types.RouteTable{},
-						}, nil
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: false,
-		},
-		{
-			name: "success - skip main route table",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeRouteTablesFunc: func(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
-						return &ec2.DescribeRouteTablesOutput{
-							RouteTables: []types.RouteTable{
-								{
-									RouteTableId: aws.String("rtb-1"),
-									Associations: []types.RouteTableAssociation{
-										{
-											Main: aws.Bool(true),
-										},
-									},
-								},
-								{
-									RouteTableId: aws.String("rtb-2"),
-									Associations: []types.RouteTableAssociation{
-										{
-											Main: aws.Bool(false),
-										},
-									},
-								},
-							},
-						}, nil
-					},
-					deleteRouteTableFunc: func(ctx context.Context, input *ec2.DeleteRouteTableInput, optFns ...func(*ec2.Options)) (*ec2.DeleteRouteTableOutput, error) {
-						if *input.RouteTableId == "rtb-2" {
-							return &ec2.DeleteRouteTableOutput{}, nil
-						}
-						return nil, fmt.Errorf("failed to delete route table")
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if err := deleteRouteTables(tt.args.ctx, tt.args.client, tt.args.vpcID); (err != nil) != tt.wantErr {
-				t.Errorf("deleteRouteTables() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
-	}
-}
-
-func Test_deleteInternetGateways(t *testing.T) {
-	type args struct {
-		ctx    context.Context
-		client EC2API
-		vpcID  string
-	}
-
-	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
-	}{
-		{
-			name: "success - multiple internet gateways",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
-						return &ec2.DescribeInternetGatewaysOutput{
-							InternetGateways: []types.InternetGateway{
-								{
-									InternetGatewayId: aws.String("igw-12345"),
-								},
-								{
-									InternetGatewayId: aws.String("igw-67890"),
-								},
-							},
-						}, nil
-					},
-					detachInternetGatewayFunc: func(ctx context.Context, input *ec2.DetachInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DetachInternetGatewayOutput, error) {
-						return &ec2.DetachInternetGatewayOutput{}, nil
-					},
-					deleteInternetGatewayFunc: func(ctx context.Context, input *ec2.DeleteInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DeleteInternetGatewayOutput, error) {
-						return &ec2.DeleteInternetGatewayOutput{}, nil
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: false,
-		},
-		{
-			name: "error detaching internet gateway",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
-						return &ec2.DescribeInternetGatewaysOutput{
-							InternetGateways: []types.InternetGateway{
-								{InternetGatewayId: aws.String("igw-12345")},
-							},
-						}, nil
-					},
-					detachInternetGatewayFunc: func(ctx context.Context, input *ec2.DetachInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DetachInternetGatewayOutput, error) {
-						return nil, fmt.Errorf("failed to detach internet gateway")
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: true,
-		},
-		{
-			name: "error deleting internet gateway",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
-						return &ec2.DescribeInternetGatewaysOutput{
-							InternetGateways: []types.InternetGateway{
-								{InternetGatewayId: aws.String("igw-12345")},
-							},
-						}, nil
-					},
-					detachInternetGatewayFunc: func(ctx context.Context, input *ec2.DetachInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DetachInternetGatewayOutput, error) {
-						return &ec2.DetachInternetGatewayOutput{}, nil
-					},
-					deleteInternetGatewayFunc: func(ctx context.Context, input *ec2.DeleteInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DeleteInternetGatewayOutput, error) {
-						return nil, fmt.Errorf("failed to delete internet gateway")
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: true,
-		},
-		{
-			name: "no internet gateways found",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
-						return &ec2.DescribeInternetGatewaysOutput{
-							InternetGateways: []types.InternetGateway{},
-						}, nil
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: false,
-		},
-		{
-			name: "error describing internet gateways",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
-						return nil, fmt.Errorf("failed to describe internet gateways")
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if err := deleteInternetGateways(tt.args.ctx, tt.args.client, tt.args.vpcID); (err != nil) != tt.wantErr {
-				t.Errorf("deleteInternetGateways() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
-	}
-}
-
-func Test_deleteSecurityGroups(t *testing.T) {
-	type args struct {
-		ctx    context.Context
-		client EC2API
-		vpcID  string
-	}
-	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
-	}{
-		{
-			name: "Successfully delete a security group",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeSecurityGroupsFunc: func(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
-						return &ec2.DescribeSecurityGroupsOutput{
-							SecurityGroups: []types.SecurityGroup{
-								{
-									GroupId:   aws.String("sg-12345"),
-									GroupName: aws.String("test-group"),
-								},
-							},
-						}, nil
-					},
-					deleteSecurityGroupFunc: func(ctx context.Context, input *ec2.DeleteSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error) {
-						return &ec2.DeleteSecurityGroupOutput{}, nil
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: false,
-		},
-		{
-			name: "Successfully delete multiple security groups",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeSecurityGroupsFunc: func(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
-						return &ec2.DescribeSecurityGroupsOutput{
-							SecurityGroups: []types.SecurityGroup{
-								{
-									GroupId:   aws.String("sg-12345"),
-									GroupName: aws.String("test-group-1"),
-								},
-								{
-									GroupId:   aws.String("sg-67890"),
-									GroupName: aws.String("test-group-2"),
-								},
-							},
-						}, nil
-					},
-					deleteSecurityGroupFunc: func(ctx context.Context, input *ec2.DeleteSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error) {
-						if *input.GroupId == "sg-12345" || *input.GroupId == "sg-67890" {
-							return &ec2.DeleteSecurityGroupOutput{}, nil
-						}
-						t.Fatalf("Unexpected security group deletion attempt for group: %s", aws.ToString(input.GroupId))
-						return nil, nil
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: false,
-		},
-		{
-			name: "Skip Default Security Group",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeSecurityGroupsFunc: func(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
-						return &ec2.DescribeSecurityGroupsOutput{
-							SecurityGroups: []types.SecurityGroup{
-								{
-									GroupId:   aws.String("sg-default"),
-									GroupName: aws.String("default"),
-								},
-							},
-						}, nil
-					},
-					deleteSecurityGroupFunc: func(ctx context.Context, input *ec2.DeleteSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error) {
-						t.Fatalf("deleteSecurityGroupFunc should not be called for the default group")
-						return nil, nil
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: false,
-		},
-		{
-			name: "No Security Groups to Delete",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeSecurityGroupsFunc: func(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
-						return &ec2.DescribeSecurityGroupsOutput{
-							SecurityGroups: []types.SecurityGroup{},
-						}, nil
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: false,
-		},
-		{
-			name: "Error in DescribeSecurityGroups",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeSecurityGroupsFunc: func(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
-						return nil, errors.New("describe security groups failed")
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: true,
-		},
-		{
-			name: "Error in DeleteSecurityGroup",
-			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeSecurityGroupsFunc: func(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
-						return &ec2.DescribeSecurityGroupsOutput{
-							SecurityGroups: []types.SecurityGroup{
-								{
-									GroupId:   aws.String("sg-12345"),
-									GroupName: aws.String("test-group"),
-								},
-							},
-						}, nil
-					},
-					deleteSecurityGroupFunc: func(ctx context.Context, input *ec2.DeleteSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error) {
-						return nil, errors.New("delete security group failed")
-					},
-				},
-				vpcID: "vpc-12345",
-			},
-			wantErr: true,
-		},
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getRegions() = %v, want %v", got, tt.want)
+			}
+		})
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if err := deleteSecurityGroups(tt.args.ctx, tt.args.client, tt.args.vpcID); (err != nil) != tt.wantErr {
-				t.Errorf("deleteSecurityGroups() error = %v, wantErr %v", err, tt.wantErr)
+}
+
+func Test_getRegions_allRegions(t *testing.T) {
+	for _, allRegions := range []bool{true, false} {
+		t.Run(fmt.Sprintf("allRegions=%v", allRegions), func(t *testing.T) {
+			var input *ec2.DescribeRegionsInput
+			client := &MockEC2Client{
+				describeRegionsFunc: func(ctx context.Context, in *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error) {
+					input = in
+					return &ec2.DescribeRegionsOutput{}, nil
+				},
+			}
+			if _, err := getRegions(context.Background(), client, allRegions); err != nil {
+				t.Fatalf("getRegions() error = %v", err)
+			}
+			if input == nil || aws.ToBool(input.AllRegions) != allRegions {
+				t.Errorf("DescribeRegionsInput = %+v, want AllRegions %v", input, allRegions)
 			}
 		})
 	}
 }
 
-func Test_deleteNetworkACLs(t *testing.T) {
+func Test_getDefaultVPCs(t *testing.T) {
 	type args struct {
 		ctx    context.Context
 		client EC2API
-		vpcID  string
 	}
 
 	tests := []struct {
 		name    string
 		args    args
+		want    []string
 		wantErr bool
 	}{
 		{
-			name: "Successfully delete non-default network ACLs",
+			name: "success - multiple default VPCs",
 			args: args{
 				ctx: context.Background(),
 				client: &MockEC2Client{
-					describeNetworkAclsFunc: func(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
-						return &ec2.DescribeNetworkAclsOutput{
-							NetworkAcls: []types.NetworkAcl{
-								{
-									NetworkAclId: aws.String("acl-12345"),
-									IsDefault:    aws.Bool(false),
-								},
+					describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+						return &ec2.DescribeVpcsOutput{
+							Vpcs: []types.Vpc{
+								{VpcId: aws.String("vpc-12345"), IsDefault: aws.Bool(true)},
+								{VpcId: aws.String("vpc-67890"), IsDefault: aws.Bool(true)},
 							},
 						}, nil
 					},
-					deleteNetworkAclFunc: func(ctx context.Context, input *ec2.DeleteNetworkAclInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkAclOutput, error) {
-						return &ec2.DeleteNetworkAclOutput{}, nil
-					},
 				},
-				vpcID: "vpc-12345",
 			},
+			want:    []string{"vpc-12345", "vpc-67890"},
 			wantErr: false,
 		},
 		{
-			name: "Successfully skip default network ACL",
+			name: "success - default VPCs across multiple pages",
 			args: args{
 				ctx: context.Background(),
 				client: &MockEC2Client{
-					describeNetworkAclsFunc: func(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
-						return &ec2.DescribeNetworkAclsOutput{
-							NetworkAcls: []types.NetworkAcl{
-								{
-									NetworkAclId: aws.String("acl-default"),
-									IsDefault:    aws.Bool(true),
+					describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+						if input.NextToken == nil {
+							return &ec2.DescribeVpcsOutput{
+								Vpcs: []types.Vpc{
+									{VpcId: aws.String("vpc-custom"), IsDefault: aws.Bool(false)},
 								},
+								NextToken: aws.String("page-2"),
+							}, nil
+						}
+						return &ec2.DescribeVpcsOutput{
+							Vpcs: []types.Vpc{
+								{VpcId: aws.String("vpc-12345"), IsDefault: aws.Bool(true)},
 							},
 						}, nil
 					},
 				},
-				vpcID: "vpc-12345",
 			},
+			want:    []string{"vpc-12345"},
 			wantErr: false,
 		},
 		{
-			name: "Error describing network ACLs",
+			name: "error fetching VPCs",
 			args: args{
 				ctx: context.Background(),
 				client: &MockEC2Client{
-					describeNetworkAclsFunc: func(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
-						return nil, fmt.Errorf("failed to describe network ACLs")
+					describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+						return nil, fmt.Errorf("failed to fetch VPCs")
 					},
 				},
-				vpcID: "vpc-12345",
 			},
+			want:    nil,
 			wantErr: true,
 		},
 		{
-			name: "Error deleting a network ACL",
+			name: "no default VPCs",
 			args: args{
 				ctx: context.Background(),
 				client: &MockEC2Client{
-					describeNetworkAclsFunc: func(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
-						return &ec2.DescribeNetworkAclsOutput{
-							NetworkAcls: []types.NetworkAcl{
-								{
-									NetworkAclId: aws.String("acl-12345"),
-									IsDefault:    aws.Bool(false),
-								},
-							},
+					describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+						return &ec2.DescribeVpcsOutput{
+							Vpcs: []types.Vpc{},
 						}, nil
 					},
-					deleteNetworkAclFunc: func(ctx context.Context, input *ec2.DeleteNetworkAclInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkAclOutput, error) {
-						return nil, fmt.Errorf("failed to delete network ACL")
+				},
+			},
+			want:    []string{},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := getDefaultVPCs(tt.args.ctx, tt.args.client, Protection{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getDefaultVPCs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getDefaultVPCs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isMainRouteTable(t *testing.T) {
+	type args struct {
+		rt types.RouteTable
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "main route table",
+			args: args{
+				rt: types.RouteTable{
+					Associations: []types.RouteTableAssociation{
+						{
+							Main: aws.Bool(true),
+						},
 					},
 				},
-				vpcID: "vpc-12345",
 			},
-			wantErr: true,
+			want: true,
 		},
 		{
-			name: "No network ACLs found",
+			name: "non-main route table",
 			args: args{
-				ctx: context.Background(),
-				client: &MockEC2Client{
-					describeNetworkAclsFunc: func(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
-						return &ec2.DescribeNetworkAclsOutput{
-							NetworkAcls: []types.NetworkAcl{},
-						}, nil
+				rt: types.RouteTable{
+					Associations: []types.RouteTableAssociation{
+						{
+							Main: aws.Bool(false),
+						},
 					},
 				},
-				vpcID: "vpc-12345",
 			},
-			wantErr: false,
+			want: false,
+		},
+		{
+			name: "empty associations",
+			args: args{
+				rt: types.RouteTable{
+					Associations: []types.RouteTableAssociation{},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "nil main association",
+			args: args{
+				rt: types.RouteTable{
+					Associations: []types.RouteTableAssociation{
+						{
+							Main: nil,
+						},
+					},
+				},
+			},
+			want: false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := deleteNetworkACLs(tt.args.ctx, tt.args.client, tt.args.vpcID); (err != nil) != tt.wantErr {
-				t.Errorf("deleteNetworkACLs() error = %v, wantErr %v", err, tt.wantErr)
+			if got := isMainRouteTable(tt.args.rt); got != tt.want {
+				t.Errorf("isMainRouteTable() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -968,6 +422,21 @@ func Test_cleanupVPCResources(t *testing.T) {
 							},
 						}, nil
 					},
+					describeNatGatewaysFunc: func(ctx context.Context, input *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+						return &ec2.DescribeNatGatewaysOutput{}, nil
+					},
+					describeVpcEndpointsFunc: func(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+						return &ec2.DescribeVpcEndpointsOutput{}, nil
+					},
+					describeNetworkInterfacesFunc: func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+						return &ec2.DescribeNetworkInterfacesOutput{}, nil
+					},
+					describeEgressOnlyInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeEgressOnlyInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+						return &ec2.DescribeEgressOnlyInternetGatewaysOutput{}, nil
+					},
+					describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+						return &ec2.DescribeVpcsOutput{Vpcs: []types.Vpc{{VpcId: aws.String(input.VpcIds[0])}}}, nil
+					},
 					describeNetworkAclsFunc: func(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
 						return &ec2.DescribeNetworkAclsOutput{
 							NetworkAcls: []types.NetworkAcl{
@@ -1025,6 +494,33 @@ func Test_cleanupVPCResources(t *testing.T) {
 					deleteInternetGatewayFunc: func(ctx context.Context, input *ec2.DeleteInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DeleteInternetGatewayOutput, error) {
 						return nil, fmt.Errorf("failed to delete internet gateway")
 					},
+					describeSubnetsFunc: func(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+						return &ec2.DescribeSubnetsOutput{}, nil
+					},
+					describeRouteTablesFunc: func(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+						return &ec2.DescribeRouteTablesOutput{}, nil
+					},
+					describeNatGatewaysFunc: func(ctx context.Context, input *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+						return &ec2.DescribeNatGatewaysOutput{}, nil
+					},
+					describeVpcEndpointsFunc: func(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+						return &ec2.DescribeVpcEndpointsOutput{}, nil
+					},
+					describeNetworkInterfacesFunc: func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+						return &ec2.DescribeNetworkInterfacesOutput{}, nil
+					},
+					describeEgressOnlyInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeEgressOnlyInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+						return &ec2.DescribeEgressOnlyInternetGatewaysOutput{}, nil
+					},
+					describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+						return &ec2.DescribeVpcsOutput{Vpcs: []types.Vpc{{VpcId: aws.String(input.VpcIds[0])}}}, nil
+					},
+					describeNetworkAclsFunc: func(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
+						return &ec2.DescribeNetworkAclsOutput{}, nil
+					},
+					describeSecurityGroupsFunc: func(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+						return &ec2.DescribeSecurityGroupsOutput{}, nil
+					},
 				},
 				vpcID: "vpc-12345",
 			},
@@ -1065,6 +561,30 @@ func Test_cleanupVPCResources(t *testing.T) {
 					detachInternetGatewayFunc: func(ctx context.Context, input *ec2.DetachInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DetachInternetGatewayOutput, error) {
 						return &ec2.DetachInternetGatewayOutput{}, nil
 					},
+					describeRouteTablesFunc: func(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+						return &ec2.DescribeRouteTablesOutput{}, nil
+					},
+					describeNatGatewaysFunc: func(ctx context.Context, input *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+						return &ec2.DescribeNatGatewaysOutput{}, nil
+					},
+					describeVpcEndpointsFunc: func(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+						return &ec2.DescribeVpcEndpointsOutput{}, nil
+					},
+					describeNetworkInterfacesFunc: func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+						return &ec2.DescribeNetworkInterfacesOutput{}, nil
+					},
+					describeEgressOnlyInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeEgressOnlyInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+						return &ec2.DescribeEgressOnlyInternetGatewaysOutput{}, nil
+					},
+					describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+						return &ec2.DescribeVpcsOutput{Vpcs: []types.Vpc{{VpcId: aws.String(input.VpcIds[0])}}}, nil
+					},
+					describeNetworkAclsFunc: func(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
+						return &ec2.DescribeNetworkAclsOutput{}, nil
+					},
+					describeSecurityGroupsFunc: func(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+						return &ec2.DescribeSecurityGroupsOutput{}, nil
+					},
 				},
 				vpcID: "vpc-12345",
 			},
@@ -1120,6 +640,27 @@ func Test_cleanupVPCResources(t *testing.T) {
 					detachInternetGatewayFunc: func(ctx context.Context, input *ec2.DetachInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DetachInternetGatewayOutput, error) {
 						return &ec2.DetachInternetGatewayOutput{}, nil
 					},
+					describeNatGatewaysFunc: func(ctx context.Context, input *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+						return &ec2.DescribeNatGatewaysOutput{}, nil
+					},
+					describeVpcEndpointsFunc: func(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+						return &ec2.DescribeVpcEndpointsOutput{}, nil
+					},
+					describeNetworkInterfacesFunc: func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+						return &ec2.DescribeNetworkInterfacesOutput{}, nil
+					},
+					describeEgressOnlyInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeEgressOnlyInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+						return &ec2.DescribeEgressOnlyInternetGatewaysOutput{}, nil
+					},
+					describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+						return &ec2.DescribeVpcsOutput{Vpcs: []types.Vpc{{VpcId: aws.String(input.VpcIds[0])}}}, nil
+					},
+					describeNetworkAclsFunc: func(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
+						return &ec2.DescribeNetworkAclsOutput{}, nil
+					},
+					describeSecurityGroupsFunc: func(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+						return &ec2.DescribeSecurityGroupsOutput{}, nil
+					},
 				},
 				vpcID: "vpc-12345",
 			},
@@ -1192,6 +733,21 @@ func Test_cleanupVPCResources(t *testing.T) {
 							},
 						}, nil
 					},
+					describeNatGatewaysFunc: func(ctx context.Context, input *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+						return &ec2.DescribeNatGatewaysOutput{}, nil
+					},
+					describeVpcEndpointsFunc: func(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+						return &ec2.DescribeVpcEndpointsOutput{}, nil
+					},
+					describeNetworkInterfacesFunc: func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+						return &ec2.DescribeNetworkInterfacesOutput{}, nil
+					},
+					describeEgressOnlyInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeEgressOnlyInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+						return &ec2.DescribeEgressOnlyInternetGatewaysOutput{}, nil
+					},
+					describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+						return &ec2.DescribeVpcsOutput{Vpcs: []types.Vpc{{VpcId: aws.String(input.VpcIds[0])}}}, nil
+					},
 					describeNetworkAclsFunc: func(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
 						return &ec2.DescribeNetworkAclsOutput{
 							NetworkAcls: []types.NetworkAcl{
@@ -1281,6 +837,21 @@ func Test_cleanupVPCResources(t *testing.T) {
 							},
 						}, nil
 					},
+					describeNatGatewaysFunc: func(ctx context.Context, input *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+						return &ec2.DescribeNatGatewaysOutput{}, nil
+					},
+					describeVpcEndpointsFunc: func(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+						return &ec2.DescribeVpcEndpointsOutput{}, nil
+					},
+					describeNetworkInterfacesFunc: func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+						return &ec2.DescribeNetworkInterfacesOutput{}, nil
+					},
+					describeEgressOnlyInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeEgressOnlyInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+						return &ec2.DescribeEgressOnlyInternetGatewaysOutput{}, nil
+					},
+					describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+						return &ec2.DescribeVpcsOutput{Vpcs: []types.Vpc{{VpcId: aws.String(input.VpcIds[0])}}}, nil
+					},
 					describeNetworkAclsFunc: func(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
 						return &ec2.DescribeNetworkAclsOutput{
 							NetworkAcls: []types.NetworkAcl{
@@ -1307,9 +878,215 @@ func Test_cleanupVPCResources(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := cleanupVPCResources(tt.args.ctx, tt.args.client, tt.args.vpcID); (err != nil) != tt.wantErr {
+			if _, err := cleanupVPCResources(tt.args.ctx, tt.args.client, tt.args.vpcID); (err != nil) != tt.wantErr {
 				t.Errorf("cleanupVPCResources() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func TestDeleteAllDefaultVPCs(t *testing.T) {
+	newRegionMock := func(vpcID string, deleteVpcErr error) *MockEC2Client {
+		return &MockEC2Client{
+			describeInstancesFunc: func(ctx context.Context, input *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+				if vpcID == "vpc-busy" {
+					return &ec2.DescribeInstancesOutput{
+						Reservations: []types.Reservation{{Instances: []types.Instance{{InstanceId: aws.String("i-12345")}}}},
+					}, nil
+				}
+				return &ec2.DescribeInstancesOutput{}, nil
+			},
+			describeNatGatewaysFunc: func(ctx context.Context, input *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+				return &ec2.DescribeNatGatewaysOutput{}, nil
+			},
+			describeVpcEndpointsFunc: func(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+				return &ec2.DescribeVpcEndpointsOutput{}, nil
+			},
+			describeNetworkInterfacesFunc: func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+				return &ec2.DescribeNetworkInterfacesOutput{}, nil
+			},
+			describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+				return &ec2.DescribeVpcsOutput{
+					Vpcs: []types.Vpc{{VpcId: aws.String(vpcID), IsDefault: aws.Bool(true)}},
+				}, nil
+			},
+			describeInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
+				return &ec2.DescribeInternetGatewaysOutput{}, nil
+			},
+			describeSubnetsFunc: func(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+				return &ec2.DescribeSubnetsOutput{
+					Subnets: []types.Subnet{{SubnetId: aws.String("subnet-1")}},
+				}, nil
+			},
+			deleteSubnetFunc: func(ctx context.Context, input *ec2.DeleteSubnetInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSubnetOutput, error) {
+				return &ec2.DeleteSubnetOutput{}, nil
+			},
+			describeRouteTablesFunc: func(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+				return &ec2.DescribeRouteTablesOutput{}, nil
+			},
+			describeEgressOnlyInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeEgressOnlyInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+				return &ec2.DescribeEgressOnlyInternetGatewaysOutput{}, nil
+			},
+			describeNetworkAclsFunc: func(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
+				return &ec2.DescribeNetworkAclsOutput{}, nil
+			},
+			describeSecurityGroupsFunc: func(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+				return &ec2.DescribeSecurityGroupsOutput{}, nil
+			},
+			deleteVpcFunc: func(ctx context.Context, input *ec2.DeleteVpcInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVpcOutput, error) {
+				return &ec2.DeleteVpcOutput{}, deleteVpcErr
+			},
+		}
+	}
+
+	clients := map[string]EC2API{
+		"us-east-1":  newRegionMock("vpc-east", nil),
+		"us-west-2":  newRegionMock("vpc-west", fmt.Errorf("DependencyViolation")),
+		"eu-north-1": newRegionMock("vpc-busy", nil),
+		"eu-west-1": &MockEC2Client{
+			describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+				return nil, fmt.Errorf("failed to fetch VPCs")
+			},
+		},
+	}
+
+	var mu sync.Mutex
+	snapshots := map[string]RegionSnapshot{}
+	backup := func(snapshot RegionSnapshot) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		snapshots[snapshot.Region] = snapshot
+		return snapshot.Region + ".json", nil
+	}
+
+	result, err := DeleteAllDefaultVPCs(context.Background(), []string{"us-west-2", "us-east-1", "eu-west-1", "eu-north-1"}, func(region string) EC2API {
+		return clients[region]
+	}, Protection{}, CleanupOptions{}, backup, 2)
+	if err == nil {
+		t.Fatalf("DeleteAllDefaultVPCs() expected an error")
+	}
+
+	wantFailed := map[string]bool{"eu-north-1": false, "eu-west-1": true, "us-east-1": false, "us-west-2": true}
+	var gotRegions []string
+	for _, region := range result.Regions {
+		gotRegions = append(gotRegions, region.Region)
+		if region.Failed() != wantFailed[region.Region] {
+			t.Errorf("region %s failed = %v, want %v", region.Region, region.Failed(), wantFailed[region.Region])
+		}
+	}
+	if want := []string{"eu-north-1", "eu-west-1", "us-east-1", "us-west-2"}; !reflect.DeepEqual(gotRegions, want) {
+		t.Errorf("DeleteAllDefaultVPCs() regions = %v, want %v", gotRegions, want)
+	}
+
+	busy := result.Regions[0].VPCs[0]
+	if busy.SkipReason != "in use by instance i-12345" || len(busy.Resources) != 0 {
+		t.Errorf("eu-north-1 VPC in use was not skipped: %+v", busy)
+	}
+
+	east := result.Regions[2]
+	if len(east.VPCs) != 1 || !east.VPCs[0].Deleted() {
+		t.Errorf("us-east-1 VPC was not deleted: %+v", east.VPCs)
+	}
+	if got := len(east.VPCs[0].Resources); got != 2 {
+		t.Errorf("us-east-1 recorded %d resource results, want 2", got)
+	}
+
+	if _, ok := snapshots["eu-north-1"]; ok {
+		t.Errorf("DeleteAllDefaultVPCs() saved a snapshot for a region with nothing to delete")
+	}
+	snapshot := snapshots["us-east-1"]
+	if len(snapshot.VPCs) != 1 || aws.ToString(snapshot.VPCs[0].Vpc.VpcId) != "vpc-east" || len(snapshot.VPCs[0].Subnets) != 1 {
+		t.Errorf("us-east-1 snapshot = %+v", snapshot)
+	}
+	if east.SnapshotPath != "us-east-1.json" {
+		t.Errorf("us-east-1 snapshot path = %q, want us-east-1.json", east.SnapshotPath)
+	}
+}
+
+func Test_deleteAcrossPages(t *testing.T) {
+	// Each describe returns one resource per page over two pages
+	page := func(token *string) (string, *string) {
+		if token == nil {
+			return "1", aws.String("page-2")
+		}
+		return "2", nil
+	}
+
+	var mu sync.Mutex
+	var deleted []string
+	deleteCall := func(id string) {
+		mu.Lock()
+		defer mu.Unlock()
+		deleted = append(deleted, id)
+	}
+	client := &MockEC2Client{
+		describeSubnetsFunc: func(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+			n, next := page(input.NextToken)
+			return &ec2.DescribeSubnetsOutput{Subnets: []types.Subnet{{SubnetId: aws.String("subnet-" + n)}}, NextToken: next}, nil
+		},
+		deleteSubnetFunc: func(ctx context.Context, input *ec2.DeleteSubnetInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSubnetOutput, error) {
+			deleteCall(*input.SubnetId)
+			return &ec2.DeleteSubnetOutput{}, nil
+		},
+		describeRouteTablesFunc: func(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+			n, next := page(input.NextToken)
+			return &ec2.DescribeRouteTablesOutput{RouteTables: []types.RouteTable{{RouteTableId: aws.String("rtb-" + n)}}, NextToken: next}, nil
+		},
+		deleteRouteTableFunc: func(ctx context.Context, input *ec2.DeleteRouteTableInput, optFns ...func(*ec2.Options)) (*ec2.DeleteRouteTableOutput, error) {
+			deleteCall(*input.RouteTableId)
+			return &ec2.DeleteRouteTableOutput{}, nil
+		},
+		describeInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
+			n, next := page(input.NextToken)
+			return &ec2.DescribeInternetGatewaysOutput{InternetGateways: []types.InternetGateway{{InternetGatewayId: aws.String("igw-" + n)}}, NextToken: next}, nil
+		},
+		detachInternetGatewayFunc: func(ctx context.Context, input *ec2.DetachInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DetachInternetGatewayOutput, error) {
+			return &ec2.DetachInternetGatewayOutput{}, nil
+		},
+		deleteInternetGatewayFunc: func(ctx context.Context, input *ec2.DeleteInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DeleteInternetGatewayOutput, error) {
+			deleteCall(*input.InternetGatewayId)
+			return &ec2.DeleteInternetGatewayOutput{}, nil
+		},
+		describeSecurityGroupsFunc: func(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+			n, next := page(input.NextToken)
+			return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: []types.SecurityGroup{{GroupId: aws.String("sg-" + n), GroupName: aws.String("group-" + n)}}, NextToken: next}, nil
+		},
+		deleteSecurityGroupFunc: func(ctx context.Context, input *ec2.DeleteSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error) {
+			deleteCall(*input.GroupId)
+			return &ec2.DeleteSecurityGroupOutput{}, nil
+		},
+		describeNatGatewaysFunc: func(ctx context.Context, input *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+			return &ec2.DescribeNatGatewaysOutput{}, nil
+		},
+		describeVpcEndpointsFunc: func(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+			return &ec2.DescribeVpcEndpointsOutput{}, nil
+		},
+		describeNetworkInterfacesFunc: func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+			return &ec2.DescribeNetworkInterfacesOutput{}, nil
+		},
+		describeEgressOnlyInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeEgressOnlyInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+			return &ec2.DescribeEgressOnlyInternetGatewaysOutput{}, nil
+		},
+		describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+			return &ec2.DescribeVpcsOutput{Vpcs: []types.Vpc{{VpcId: aws.String(input.VpcIds[0])}}}, nil
+		},
+		describeNetworkAclsFunc: func(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
+			n, next := page(input.NextToken)
+			return &ec2.DescribeNetworkAclsOutput{NetworkAcls: []types.NetworkAcl{{NetworkAclId: aws.String("acl-" + n), IsDefault: aws.Bool(false)}}, NextToken: next}, nil
+		},
+		deleteNetworkAclFunc: func(ctx context.Context, input *ec2.DeleteNetworkAclInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkAclOutput, error) {
+			deleteCall(*input.NetworkAclId)
+			return &ec2.DeleteNetworkAclOutput{}, nil
+		},
+	}
+
+	if _, err := cleanupVPCResources(context.Background(), client, "vpc-12345"); err != nil {
+		t.Fatalf("cleanupVPCResources() error = %v", err)
+	}
+
+	sort.Strings(deleted)
+	want := []string{"acl-1", "acl-2", "igw-1", "igw-2", "rtb-1", "rtb-2", "sg-1", "sg-2", "subnet-1", "subnet-2"}
+	if !reflect.DeepEqual(deleted, want) {
+		t.Errorf("cleanupVPCResources() deleted %v, want %v", deleted, want)
+	}
+}
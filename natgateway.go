@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// How long to wait for a NAT gateway or VPC endpoint to finish deleting, and
+// how often to check. NAT gateways usually take about a minute.
+const (
+	deleteWaitTimeout  = 10 * time.Minute
+	deleteWaitInterval = 10 * time.Second
+)
+
+// natGatewayAllocations lists the Elastic IP allocations attached to a NAT
+// gateway. Private NAT gateways have none.
+func natGatewayAllocations(nat types.NatGateway) []string {
+	var ids []string
+	for _, address := range nat.NatGatewayAddresses {
+		if id := aws.ToString(address.AllocationId); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Delete a NAT gateway and wait until it has gone, as its network interface
+// holds up the subnet and its Elastic IP until then
+func deleteNatGateway(ctx context.Context, client EC2API, natGatewayID string) error {
+	if _, err := client.DeleteNatGateway(ctx, &ec2.DeleteNatGatewayInput{NatGatewayId: aws.String(natGatewayID)}); err != nil {
+		return err
+	}
+	loggerFrom(ctx).Info("waiting for NAT gateway to be deleted", logKeyResourceType, resourceNatGateway, logKeyResourceID, natGatewayID)
+	return waitUntil(ctx, deleteWaitTimeout, deleteWaitInterval, func() (bool, error) {
+		out, err := client.DescribeNatGateways(ctx, &ec2.DescribeNatGatewaysInput{NatGatewayIds: []string{natGatewayID}})
+		if isNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		for _, nat := range out.NatGateways {
+			if nat.State != types.NatGatewayStateDeleted {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+// Release an Elastic IP that was attached to a deleted NAT gateway
+func releaseAddress(ctx context.Context, client EC2API, allocationID string) error {
+	_, err := client.ReleaseAddress(ctx, &ec2.ReleaseAddressInput{AllocationId: aws.String(allocationID)})
+	return err
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+)
+
+// OrganizationsAPI defines methods to use from the Organizations api
+type OrganizationsAPI interface {
+	ListAccounts(ctx context.Context, input *organizations.ListAccountsInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsOutput, error)
+	ListAccountsForParent(ctx context.Context, input *organizations.ListAccountsForParentInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsForParentOutput, error)
+	ListOrganizationalUnitsForParent(ctx context.Context, input *organizations.ListOrganizationalUnitsForParentInput, optFns ...func(*organizations.Options)) (*organizations.ListOrganizationalUnitsForParentOutput, error)
+}
+
+// OrganizationsClient implements OrganizationsAPI and wraps the real Organizations client
+type OrganizationsClient struct {
+	Client *organizations.Client
+}
+
+func (c *OrganizationsClient) ListAccounts(ctx context.Context, input *organizations.ListAccountsInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsOutput, error) {
+	return c.Client.ListAccounts(ctx, input, optFns...)
+}
+
+func (c *OrganizationsClient) ListAccountsForParent(ctx context.Context, input *organizations.ListAccountsForParentInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsForParentOutput, error) {
+	return c.Client.ListAccountsForParent(ctx, input, optFns...)
+}
+
+func (c *OrganizationsClient) ListOrganizationalUnitsForParent(ctx context.Context, input *organizations.ListOrganizationalUnitsForParentInput, optFns ...func(*organizations.Options)) (*organizations.ListOrganizationalUnitsForParentOutput, error) {
+	return c.Client.ListOrganizationalUnitsForParent(ctx, input, optFns...)
+}
+
+// Mocks
+// MockOrganizationsClient a mock implementation of OrganizationsAPI
+type MockOrganizationsClient struct {
+	listAccountsFunc                     func(ctx context.Context, input *organizations.ListAccountsInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsOutput, error)
+	listAccountsForParentFunc            func(ctx context.Context, input *organizations.ListAccountsForParentInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsForParentOutput, error)
+	listOrganizationalUnitsForParentFunc func(ctx context.Context, input *organizations.ListOrganizationalUnitsForParentInput, optFns ...func(*organizations.Options)) (*organizations.ListOrganizationalUnitsForParentOutput, error)
+}
+
+func (m *MockOrganizationsClient) ListAccounts(ctx context.Context, input *organizations.ListAccountsInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsOutput, error) {
+	return m.listAccountsFunc(ctx, input, optFns...)
+}
+
+func (m *MockOrganizationsClient) ListAccountsForParent(ctx context.Context, input *organizations.ListAccountsForParentInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsForParentOutput, error) {
+	return m.listAccountsForParentFunc(ctx, input, optFns...)
+}
+
+func (m *MockOrganizationsClient) ListOrganizationalUnitsForParent(ctx context.Context, input *organizations.ListOrganizationalUnitsForParentInput, optFns ...func(*organizations.Options)) (*organizations.ListOrganizationalUnitsForParentOutput, error) {
+	return m.listOrganizationalUnitsForParentFunc(ctx, input, optFns...)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// VPCPlan lists the resources that would be removed from a default VPC. A VPC
+// with SkipReason set would be left alone, and Protected marks one that
+// --protect-vpc or --protect-tag matched. ElasticIPs are the allocations
+// released once the NAT gateways using them are gone. CidrBlockAssociations
+// are the IPv6 blocks of the subnets and the secondary and IPv6 blocks of the
+// VPC, which are disassociated before it is deleted. NetworkInterfaces lists
+// every interface in the VPC so a saved plan can tell when it has changed,
+// though only ones left behind are deleted. Steps are the calls that delete
+// the VPC, in an order its delete graph allows.
+type VPCPlan struct {
+	VpcID                      string     `json:"vpcId"`
+	SkipReason                 string     `json:"skipReason,omitempty"`
+	Protected                  bool       `json:"protected,omitempty"`
+	InternetGateways           []string   `json:"internetGateways"`
+	Subnets                    []string   `json:"subnets"`
+	RouteTables                []string   `json:"routeTables"`
+	NetworkACLs                []string   `json:"networkAcls"`
+	SecurityGroups             []string   `json:"securityGroups"`
+	NatGateways                []string   `json:"natGateways,omitempty"`
+	VpcEndpoints               []string   `json:"vpcEndpoints,omitempty"`
+	ElasticIPs                 []string   `json:"elasticIps,omitempty"`
+	NetworkInterfaces          []string   `json:"networkInterfaces,omitempty"`
+	EgressOnlyInternetGateways []string   `json:"egressOnlyInternetGateways,omitempty"`
+	CidrBlockAssociations      []string   `json:"cidrBlockAssociations,omitempty"`
+	Steps                      []PlanStep `json:"steps,omitempty"`
+}
+
+// RegionPlan groups the VPC plans for a single region
+type RegionPlan struct {
+	Region string    `json:"region"`
+	VPCs   []VPCPlan `json:"vpcs"`
+	Err    error     `json:"-"`
+}
+
+// Describe everything cleanupVPCResources would delete in a VPC without
+// deleting any of it
+func planVPCResources(ctx context.Context, client EC2API, vpcID string) (VPCPlan, error) {
+	resources, err := describeVPCResources(ctx, client, vpcID)
+	if err != nil {
+		return VPCPlan{VpcID: vpcID}, err
+	}
+	return resources.plan(vpcID), nil
+}
+
+// Plan a VPC along with the steps that delete it
+func planVPC(ctx context.Context, client EC2API, vpcID string) (VPCPlan, error) {
+	resources, err := describeVPCResources(ctx, client, vpcID)
+	if err != nil {
+		return VPCPlan{VpcID: vpcID}, err
+	}
+	plan := resources.plan(vpcID)
+	graph, err := newDeleteGraph(resources)
+	if err != nil {
+		return plan, err
+	}
+	if plan.Steps, err = graph.order(); err != nil {
+		return plan, err
+	}
+	plan.Steps = append(plan.Steps, PlanStep{Action: actionDelete, Type: resourceVPC, ID: vpcID})
+	return plan, nil
+}
+
+// Build the plan for every default VPC in a region
+func planRegion(ctx context.Context, client EC2API, region string, protection Protection, cleanup CleanupOptions) RegionPlan {
+	regionPlan := RegionPlan{Region: region}
+
+	vpcs, protected, err := getDefaultVPCs(ctx, client, protection)
+	if err != nil {
+		regionPlan.Err = fmt.Errorf("failed to fetch default VPCs in region %s: %w", region, err)
+		return regionPlan
+	}
+	for _, p := range protected {
+		regionPlan.VPCs = append(regionPlan.VPCs, VPCPlan{VpcID: p.VpcID, SkipReason: p.Reason, Protected: true})
+	}
+
+	for _, vpcID := range vpcs {
+		workloads, err := findVPCWorkloads(ctx, client, vpcID, cleanup)
+		if err != nil {
+			regionPlan.Err = fmt.Errorf("failed to check whether VPC %s in region %s is in use: %w", vpcID, region, err)
+			return regionPlan
+		}
+		if len(workloads) > 0 {
+			regionPlan.VPCs = append(regionPlan.VPCs, VPCPlan{VpcID: vpcID, SkipReason: inUseReason(workloads)})
+			continue
+		}
+
+		plan, err := planVPC(ctx, client, vpcID)
+		if err != nil {
+			regionPlan.Err = fmt.Errorf("failed to plan VPC %s in region %s: %w", vpcID, region, err)
+			return regionPlan
+		}
+		regionPlan.VPCs = append(regionPlan.VPCs, plan)
+	}
+
+	return regionPlan
+}
+
+// PlanAllDefaultVPCs describes what DeleteAllDefaultVPCs would delete in each
+// region, without issuing any Delete or Detach calls. At most concurrency
+// regions are described at once.
+func PlanAllDefaultVPCs(ctx context.Context, regions []string, newClient ClientFactory, protection Protection, cleanup CleanupOptions, concurrency int) ([]RegionPlan, error) {
+	plans := make([]RegionPlan, len(regions))
+
+	runBounded(len(regions), concurrency, func(i int) {
+		plans[i] = planRegion(ctx, newClient(regions[i]), regions[i], protection, cleanup)
+	})
+
+	sort.Slice(plans, func(i, j int) bool { return plans[i].Region < plans[j].Region })
+
+	var errs []error
+	for _, plan := range plans {
+		if plan.Err != nil {
+			errs = append(errs, plan.Err)
+		}
+	}
+	return plans, errors.Join(errs...)
+}
+
+// Write a human readable plan grouped by region and VPC
+func printPlan(w io.Writer, plans []RegionPlan) {
+	for _, regionPlan := range plans {
+		fmt.Fprintf(w, "Region %s\n", regionPlan.Region)
+		if regionPlan.Err != nil {
+			fmt.Fprintf(w, "  Error: %v\n", regionPlan.Err)
+			continue
+		}
+		if len(regionPlan.VPCs) == 0 {
+			fmt.Fprintln(w, "  No default VPCs")
+			continue
+		}
+
+		for _, plan := range regionPlan.VPCs {
+			if plan.Protected {
+				fmt.Fprintf(w, "  VPC %s: protected, %s\n", plan.VpcID, plan.SkipReason)
+				continue
+			}
+			if plan.SkipReason != "" {
+				fmt.Fprintf(w, "  VPC %s: skipped, %s\n", plan.VpcID, plan.SkipReason)
+				continue
+			}
+			fmt.Fprintf(w, "  VPC %s\n", plan.VpcID)
+			for _, step := range plan.Steps {
+				switch {
+				case step.Type == resourceNetworkInterface && step.Action == actionWait:
+					fmt.Fprintf(w, "    wait for Lambda to remove network interface: %s\n", step.ID)
+				case step.Type == resourceNetworkInterface && step.Action == actionDelete:
+					fmt.Fprintf(w, "    delete network interface: %s\n", step.ID)
+				}
+			}
+			for _, id := range plan.VpcEndpoints {
+				fmt.Fprintf(w, "    delete VPC endpoint: %s\n", id)
+			}
+			for _, id := range plan.NatGateways {
+				fmt.Fprintf(w, "    delete NAT gateway: %s\n", id)
+			}
+			for _, id := range plan.ElasticIPs {
+				fmt.Fprintf(w, "    release Elastic IP: %s\n", id)
+			}
+			for _, id := range plan.InternetGateways {
+				fmt.Fprintf(w, "    detach and delete internet gateway: %s\n", id)
+			}
+			for _, id := range plan.EgressOnlyInternetGateways {
+				fmt.Fprintf(w, "    delete egress-only internet gateway: %s\n", id)
+			}
+			for _, id := range plan.Subnets {
+				fmt.Fprintf(w, "    delete subnet: %s\n", id)
+			}
+			for _, id := range plan.RouteTables {
+				fmt.Fprintf(w, "    delete route table: %s\n", id)
+			}
+			for _, id := range plan.NetworkACLs {
+				fmt.Fprintf(w, "    delete network ACL: %s\n", id)
+			}
+			for _, id := range plan.CidrBlockAssociations {
+				fmt.Fprintf(w, "    disassociate CIDR block: %s\n", id)
+			}
+			for _, step := range plan.Steps {
+				if step.Action == actionRevoke {
+					fmt.Fprintf(w, "    revoke references to other groups in security group: %s\n", step.ID)
+				}
+			}
+			for _, id := range plan.SecurityGroups {
+				fmt.Fprintf(w, "    delete security group: %s\n", id)
+			}
+			fmt.Fprintf(w, "    delete VPC: %s\n", plan.VpcID)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func Test_planVPCResources(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		client EC2API
+		vpcID  string
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    VPCPlan
+		wantErr bool
+	}{
+		{
+			name: "success - skips main route table, default ACL and default security group",
+			args: args{
+				ctx: context.Background(),
+				client: &MockEC2Client{
+					describeInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
+						return &ec2.DescribeInternetGatewaysOutput{
+							InternetGateways: []types.InternetGateway{
+								{InternetGatewayId: aws.String("igw-12345")},
+							},
+						}, nil
+					},
+					describeSubnetsFunc: func(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+						return &ec2.DescribeSubnetsOutput{
+							Subnets: []types.Subnet{
+								{SubnetId: aws.String("subnet-1")},
+								{SubnetId: aws.String("subnet-2")},
+							},
+						}, nil
+					},
+					describeRouteTablesFunc: func(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+						return &ec2.DescribeRouteTablesOutput{
+							RouteTables: []types.RouteTable{
+								{
+									RouteTableId: aws.String("rtb-main"),
+									Associations: []types.RouteTableAssociation{{Main: aws.Bool(true)}},
+								},
+								{RouteTableId: aws.String("rtb-1")},
+							},
+						}, nil
+					},
+					describeNatGatewaysFunc: func(ctx context.Context, input *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+						return &ec2.DescribeNatGatewaysOutput{}, nil
+					},
+					describeVpcEndpointsFunc: func(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+						return &ec2.DescribeVpcEndpointsOutput{}, nil
+					},
+					describeNetworkInterfacesFunc: func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+						return &ec2.DescribeNetworkInterfacesOutput{}, nil
+					},
+					describeEgressOnlyInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeEgressOnlyInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+						return &ec2.DescribeEgressOnlyInternetGatewaysOutput{}, nil
+					},
+					describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+						return &ec2.DescribeVpcsOutput{Vpcs: []types.Vpc{{VpcId: aws.String(input.VpcIds[0])}}}, nil
+					},
+					describeNetworkAclsFunc: func(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
+						return &ec2.DescribeNetworkAclsOutput{
+							NetworkAcls: []types.NetworkAcl{
+								{NetworkAclId: aws.String("acl-default"), IsDefault: aws.Bool(true)},
+								{NetworkAclId: aws.String("acl-1"), IsDefault: aws.Bool(false)},
+							},
+						}, nil
+					},
+					describeSecurityGroupsFunc: func(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+						return &ec2.DescribeSecurityGroupsOutput{
+							SecurityGroups: []types.SecurityGroup{
+								{GroupId: aws.String("sg-default"), GroupName: aws.String("default")},
+								{GroupId: aws.String("sg-1"), GroupName: aws.String("web")},
+							},
+						}, nil
+					},
+				},
+				vpcID: "vpc-12345",
+			},
+			want: VPCPlan{
+				VpcID:            "vpc-12345",
+				InternetGateways: []string{"igw-12345"},
+				Subnets:          []string{"subnet-1", "subnet-2"},
+				RouteTables:      []string{"rtb-1"},
+				NetworkACLs:      []string{"acl-1"},
+				SecurityGroups:   []string{"sg-1"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "error describing subnets",
+			args: args{
+				ctx: context.Background(),
+				client: &MockEC2Client{
+					describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+						return &ec2.DescribeVpcsOutput{Vpcs: []types.Vpc{{VpcId: aws.String(input.VpcIds[0])}}}, nil
+					},
+					describeInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
+						return &ec2.DescribeInternetGatewaysOutput{}, nil
+					},
+					describeSubnetsFunc: func(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+						return nil, fmt.Errorf("failed to fetch subnets")
+					},
+				},
+				vpcID: "vpc-12345",
+			},
+			want:    VPCPlan{VpcID: "vpc-12345"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := planVPCResources(tt.args.ctx, tt.args.client, tt.args.vpcID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("planVPCResources() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planVPCResources() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_printPlan(t *testing.T) {
+	plans := []RegionPlan{
+		{
+			Region: "eu-west-1",
+		},
+		{
+			Region: "us-east-1",
+			VPCs: []VPCPlan{
+				{
+					VpcID:            "vpc-12345",
+					InternetGateways: []string{"igw-12345"},
+					Subnets:          []string{"subnet-1"},
+					SecurityGroups:   []string{"sg-1"},
+				},
+			},
+		},
+		{
+			Region: "us-west-2",
+			Err:    fmt.Errorf("access denied"),
+		},
+	}
+
+	want := `Region eu-west-1
+  No default VPCs
+Region us-east-1
+  VPC vpc-12345
+    detach and delete internet gateway: igw-12345
+    delete subnet: subnet-1
+    delete security group: sg-1
+    delete VPC: vpc-12345
+Region us-west-2
+  Error: access denied
+`
+
+	var buf bytes.Buffer
+	printPlan(&buf, plans)
+	if got := buf.String(); got != want {
+		t.Errorf("printPlan() = %q, want %q", got, want)
+	}
+}
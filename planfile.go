@@ -0,0 +1,479 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/smithy-go"
+)
+
+// planFileVersion changes whenever the layout of a saved plan does
+const planFileVersion = 1
+
+// PlanStep is a single call apply makes against a resource
+type PlanStep struct {
+	Action string `json:"action"`
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+}
+
+// PlannedRegion is every VPC a saved plan deletes in one region
+type PlannedRegion struct {
+	Region string    `json:"region"`
+	VPCs   []VPCPlan `json:"vpcs"`
+}
+
+// PlannedAccount is every region a saved plan touches in one account
+type PlannedAccount struct {
+	AccountID   string          `json:"accountId"`
+	AccountName string          `json:"accountName,omitempty"`
+	Regions     []PlannedRegion `json:"regions"`
+}
+
+// PlanFile is a plan written by plan --out for apply to run later
+type PlanFile struct {
+	Version   int              `json:"version"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Accounts  []PlannedAccount `json:"accounts"`
+}
+
+// PlanCheck is the outcome of comparing a region against a saved plan.
+// Problems lists every way the region has changed since.
+type PlanCheck struct {
+	Region   string
+	Problems []string
+	Err      error
+}
+
+// OK reports whether the region still matches the plan
+func (c PlanCheck) OK() bool {
+	return c.Err == nil && len(c.Problems) == 0
+}
+
+// newPlannedAccount keeps the VPCs apply would delete from an account's
+// plans. Skipped and protected VPCs, and regions left with none, are dropped.
+func newPlannedAccount(account Account, plans []RegionPlan) PlannedAccount {
+	planned := PlannedAccount{AccountID: account.ID, AccountName: account.Name, Regions: []PlannedRegion{}}
+	for _, plan := range plans {
+		region := PlannedRegion{Region: plan.Region}
+		for _, vpc := range plan.VPCs {
+			if vpc.SkipReason == "" {
+				region.VPCs = append(region.VPCs, vpc)
+			}
+		}
+		if len(region.VPCs) > 0 {
+			planned.Regions = append(planned.Regions, region)
+		}
+	}
+	return planned
+}
+
+// Write a plan as JSON to path
+func writePlanFile(path string, plan PlanFile) error {
+	plan.Version = planFileVersion
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+// savePlan writes a plan for the given accounts to path and says where on w
+func savePlan(w io.Writer, path string, accounts []PlannedAccount) error {
+	if err := writePlanFile(path, PlanFile{CreatedAt: time.Now().UTC(), Accounts: accounts}); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Plan saved to %s, run it with: remove-all-default-vpc apply %s\n", path, path)
+	return nil
+}
+
+// Read a plan written by writePlanFile
+func readPlanFile(path string) (PlanFile, error) {
+	var plan PlanFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return plan, err
+	}
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return plan, fmt.Errorf("failed to read plan %s: %w", path, err)
+	}
+	if plan.Version != planFileVersion {
+		return plan, fmt.Errorf("plan %s has version %d, want %d", path, plan.Version, planFileVersion)
+	}
+	return plan, nil
+}
+
+// Describe one way a VPC has gained resources of a type since it was planned
+func gained(vpcID, resourceType string, planned, current []string) []string {
+	var problems []string
+	for _, id := range current {
+		if !slices.Contains(planned, id) {
+			problems = append(problems, fmt.Sprintf("VPC %s has a new %s %s", vpcID, resourceType, id))
+		}
+	}
+	return problems
+}
+
+// Describe a planned VPC again and list every way it has changed that makes
+// the plan unsafe: the VPC is gone, is now protected, or has resources the
+// plan doesn't know about. Resources that have since disappeared are fine.
+func checkPlannedVPC(ctx context.Context, client EC2API, planned VPCPlan, protection Protection) ([]string, error) {
+	vpc, err := describeVPC(ctx, client, planned.VpcID)
+	if isNotFound(err) {
+		return []string{fmt.Sprintf("VPC %s no longer exists", planned.VpcID)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if reason := protection.reason(vpc); reason != "" {
+		return []string{fmt.Sprintf("VPC %s is now %s", planned.VpcID, reason)}, nil
+	}
+
+	current, err := planVPCResources(ctx, client, planned.VpcID)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	problems = append(problems, gained(planned.VpcID, "internet gateway", planned.InternetGateways, current.InternetGateways)...)
+	problems = append(problems, gained(planned.VpcID, "subnet", planned.Subnets, current.Subnets)...)
+	problems = append(problems, gained(planned.VpcID, "route table", planned.RouteTables, current.RouteTables)...)
+	problems = append(problems, gained(planned.VpcID, "network ACL", planned.NetworkACLs, current.NetworkACLs)...)
+	problems = append(problems, gained(planned.VpcID, "security group", planned.SecurityGroups, current.SecurityGroups)...)
+	problems = append(problems, gained(planned.VpcID, "NAT gateway", planned.NatGateways, current.NatGateways)...)
+	problems = append(problems, gained(planned.VpcID, "VPC endpoint", planned.VpcEndpoints, current.VpcEndpoints)...)
+	problems = append(problems, gained(planned.VpcID, "network interface", planned.NetworkInterfaces, current.NetworkInterfaces)...)
+	problems = append(problems, gained(planned.VpcID, "egress-only internet gateway", planned.EgressOnlyInternetGateways, current.EgressOnlyInternetGateways)...)
+	problems = append(problems, gained(planned.VpcID, "CIDR block association", planned.CidrBlockAssociations, current.CidrBlockAssociations)...)
+	return problems, nil
+}
+
+// Compare every VPC in a planned region against what is there now
+func checkPlannedRegion(ctx context.Context, client EC2API, planned PlannedRegion, protection Protection) PlanCheck {
+	ctx = withLogAttrs(ctx, logKeyRegion, planned.Region)
+	check := PlanCheck{Region: planned.Region}
+	for _, vpc := range planned.VPCs {
+		problems, err := checkPlannedVPC(ctx, client, vpc, protection)
+		if err != nil {
+			check.Err = fmt.Errorf("failed to check VPC %s in region %s against the plan: %w", vpc.VpcID, planned.Region, err)
+			return check
+		}
+		check.Problems = append(check.Problems, problems...)
+	}
+	return check
+}
+
+// isNotFound reports whether err says the resource doesn't exist, such as
+// InvalidSubnetID.NotFound. NAT gateways use NatGatewayNotFound instead.
+func isNotFound(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return strings.HasSuffix(apiErr.ErrorCode(), ".NotFound") || apiErr.ErrorCode() == "NatGatewayNotFound"
+}
+
+// Make a single call from a saved plan or a delete graph and record its
+// outcome
+func runStep(ctx context.Context, client EC2API, vpcID string, step PlanStep) ResourceResult {
+	result := newResourceResult(step.Type, step.ID, step.Action, nil)
+	var err error
+	switch {
+	case step.Type == resourceInternetGateway && step.Action == actionDetach:
+		_, err = client.DetachInternetGateway(ctx, &ec2.DetachInternetGatewayInput{
+			InternetGatewayId: aws.String(step.ID),
+			VpcId:             aws.String(vpcID),
+		})
+	case step.Type == resourceInternetGateway && step.Action == actionDelete:
+		_, err = client.DeleteInternetGateway(ctx, &ec2.DeleteInternetGatewayInput{InternetGatewayId: aws.String(step.ID)})
+	case step.Type == resourceSubnet && step.Action == actionDelete:
+		err = deleteSubnet(ctx, client, step.ID)
+	case step.Type == resourceRouteTable && step.Action == actionDelete:
+		_, err = client.DeleteRouteTable(ctx, &ec2.DeleteRouteTableInput{RouteTableId: aws.String(step.ID)})
+	case step.Type == resourceNetworkACL && step.Action == actionDelete:
+		_, err = client.DeleteNetworkAcl(ctx, &ec2.DeleteNetworkAclInput{NetworkAclId: aws.String(step.ID)})
+	case step.Type == resourceSecurityGroup && step.Action == actionRevoke:
+		result.Rules, err = revokeGroupReferences(ctx, client, vpcID, step.ID)
+	case step.Type == resourceSecurityGroup && step.Action == actionDelete:
+		_, err = client.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{GroupId: aws.String(step.ID)})
+	case step.Type == resourceVpcEndpoint && step.Action == actionDelete:
+		err = deleteVpcEndpoint(ctx, client, step.ID)
+	case step.Type == resourceNatGateway && step.Action == actionDelete:
+		err = deleteNatGateway(ctx, client, step.ID)
+	case step.Type == resourceElasticIP && step.Action == actionRelease:
+		err = releaseAddress(ctx, client, step.ID)
+	case step.Type == resourceNetworkInterface && step.Action == actionDelete:
+		err = deleteNetworkInterface(ctx, client, step.ID)
+	case step.Type == resourceNetworkInterface && step.Action == actionWait:
+		err = waitForLambdaENI(ctx, client, step.ID)
+	case step.Type == resourceEgressOnlyInternetGateway && step.Action == actionDelete:
+		err = deleteEgressOnlyInternetGateway(ctx, client, step.ID)
+	case step.Type == resourceSubnetCidrBlock && step.Action == actionDisassociate:
+		err = disassociateSubnetCidrBlock(ctx, client, step.ID)
+	case step.Type == resourceVpcCidrBlock && step.Action == actionDisassociate:
+		err = disassociateVpcCidrBlock(ctx, client, step.ID)
+	case step.Type == resourceVPC && step.Action == actionDelete:
+		_, err = client.DeleteVpc(ctx, &ec2.DeleteVpcInput{VpcId: aws.String(step.ID)})
+	default:
+		result.Err = fmt.Errorf("unknown plan step %s %s %s", step.Action, step.Type, step.ID)
+		return result
+	}
+	if isNotFound(err) {
+		loggerFrom(ctx).Info("already gone", logKeyResourceType, step.Type, logKeyResourceID, step.ID)
+		return result
+	}
+	if err != nil {
+		result.Err = fmt.Errorf("failed to %s %s %s: %w", step.Action, step.Type, step.ID, err)
+		return result
+	}
+	if len(result.Rules) > 0 {
+		loggerFrom(ctx).Info("revoked references", logKeyResourceType, step.Type, logKeyResourceID, step.ID, "rules", result.Rules)
+		return result
+	}
+	done := "deleted"
+	switch step.Action {
+	case actionDetach:
+		done = "detached"
+	case actionRevoke:
+		done = "nothing to revoke"
+	case actionRelease:
+		done = "released"
+	case actionWait:
+		done = "gone"
+	case actionDisassociate:
+		done = "disassociated"
+	}
+	loggerFrom(ctx).Info(done, logKeyResourceType, step.Type, logKeyResourceID, step.ID)
+	return result
+}
+
+// Run a planned VPC's steps in order, stopping at the first failure
+func applyPlannedVPC(ctx context.Context, client EC2API, region string, planned VPCPlan) VPCResult {
+	ctx = withLogAttrs(ctx, logKeyVPC, planned.VpcID)
+	vpcResult := VPCResult{VpcID: planned.VpcID}
+	for _, step := range planned.Steps {
+		result := runStep(ctx, client, planned.VpcID, step)
+		vpcResult.Resources = append(vpcResult.Resources, result)
+		if result.Err != nil {
+			vpcResult.Err = fmt.Errorf("region %s: %w", region, result.Err)
+			loggerFrom(ctx).Error("failed to apply plan", logKeyError, result.Err)
+			return vpcResult
+		}
+	}
+	return vpcResult
+}
+
+// Snapshot a planned region with backup and then apply the plan to each of
+// its VPCs. Nothing is deleted if the snapshot fails.
+func applyPlannedRegion(ctx context.Context, client EC2API, planned PlannedRegion, backup SnapshotWriter) RegionResult {
+	ctx = withLogAttrs(ctx, logKeyRegion, planned.Region)
+	result := RegionResult{Region: planned.Region}
+
+	snapshot := RegionSnapshot{Region: planned.Region}
+	for _, vpc := range planned.VPCs {
+		vpcSnapshot, err := snapshotVPC(ctx, client, vpc.VpcID)
+		if err != nil {
+			result.Err = fmt.Errorf("failed to snapshot VPC %s in region %s, nothing was deleted: %w", vpc.VpcID, planned.Region, err)
+			return result
+		}
+		snapshot.VPCs = append(snapshot.VPCs, vpcSnapshot)
+	}
+	var err error
+	if result.SnapshotPath, err = backup(snapshot); err != nil {
+		result.Err = fmt.Errorf("failed to save snapshot for region %s, nothing was deleted: %w", planned.Region, err)
+		return result
+	}
+	loggerFrom(ctx).Info("saved snapshot", "path", result.SnapshotPath)
+
+	for _, vpc := range planned.VPCs {
+		result.VPCs = append(result.VPCs, applyPlannedVPC(ctx, client, planned.Region, vpc))
+	}
+	return result
+}
+
+// regionPlan turns a planned region back into the RegionPlan preflight takes
+func (r PlannedRegion) regionPlan() RegionPlan {
+	return RegionPlan{Region: r.Region, VPCs: r.VPCs}
+}
+
+// hasPlannedVPCs reports whether a saved plan deletes anything at all
+func hasPlannedVPCs(plan PlanFile) bool {
+	for _, account := range plan.Accounts {
+		if len(account.Regions) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Write the VPCs a saved plan deletes, by account and region
+func printPlanFile(w io.Writer, plan PlanFile) {
+	for _, account := range plan.Accounts {
+		fmt.Fprintf(w, "Account %s\n", Account{ID: account.AccountID, Name: account.AccountName})
+		for _, region := range account.Regions {
+			fmt.Fprintf(w, "Region %s\n", region.Region)
+			for _, vpc := range region.VPCs {
+				fmt.Fprintf(w, "  VPC %s: %d steps\n", vpc.VpcID, len(vpc.Steps))
+			}
+		}
+	}
+}
+
+// Write each region's check against the plan
+func printPlanChecks(w io.Writer, checks []PlanCheck) {
+	for _, check := range checks {
+		switch {
+		case check.Err != nil:
+			fmt.Fprintf(w, "Region %s: Error: %v\n", check.Region, check.Err)
+		case check.OK():
+			fmt.Fprintf(w, "Region %s: matches the plan\n", check.Region)
+		default:
+			fmt.Fprintf(w, "Region %s: changed since the plan was made\n", check.Region)
+		}
+		for _, problem := range check.Problems {
+			fmt.Fprintf(w, "  %s\n", problem)
+		}
+	}
+}
+
+// planTargets resolves a client factory for every account in a saved plan.
+// Without --org the plan must be for the credentials' own account.
+func planTargets(ctx context.Context, cfg aws.Config, plan PlanFile, opts runOptions) ([]accountTarget, error) {
+	targets := make([]accountTarget, len(plan.Accounts))
+	for i, planned := range plan.Accounts {
+		account := Account{ID: planned.AccountID, Name: planned.AccountName}
+		targets[i] = accountTarget{Account: account}
+		for _, region := range planned.Regions {
+			targets[i].Regions.Regions = append(targets[i].Regions.Regions, region.Region)
+		}
+		if opts.Organization.Enabled {
+			targets[i].NewClient = withRetry(NewClientFactory(assumeRoleConfig(cfg, account, opts.Organization), opts.RateLimiter.apiOption()), opts.Retry)
+		}
+	}
+	if opts.Organization.Enabled {
+		return targets, nil
+	}
+
+	accountID, err := callerAccountID(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) != 1 || targets[0].Account.ID != accountID {
+		var planned []string
+		for _, target := range targets {
+			planned = append(planned, target.Account.ID)
+		}
+		return nil, fmt.Errorf("the plan is for account %s but the credentials are for %s, pass --org to apply a plan across accounts", strings.Join(planned, ", "), accountID)
+	}
+	targets[0].NewClient = withRetry(NewClientFactory(cfg, opts.RateLimiter.apiOption()), opts.Retry)
+	return targets, nil
+}
+
+// runPlanFile applies a saved plan, recording the outcome in report. Every
+// region is checked against the plan first and nothing is deleted unless
+// all of them still match.
+func runPlanFile(ctx context.Context, cfg aws.Config, opts runOptions, report *Report) error {
+	plan, err := readPlanFile(opts.PlanFile)
+	if err != nil {
+		return err
+	}
+	targets, err := planTargets(ctx, cfg, plan, opts)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		report.Accounts = append(report.Accounts, newAccountReport(target.Account, nil, nil))
+	}
+
+	checks := make([][]PlanCheck, len(targets))
+	for t, target := range targets {
+		checks[t] = make([]PlanCheck, len(target.Regions.Regions))
+	}
+	forEachAccountRegion(ctx, targets, opts.Concurrency, func(ctx context.Context, t, r int) {
+		planned := plan.Accounts[t].Regions[r]
+		checks[t][r] = checkPlannedRegion(ctx, targets[t].NewClient(planned.Region), planned, opts.Protection)
+	})
+	var errs []error
+	for t, target := range targets {
+		fmt.Fprintf(opts.Out, "Account %s\n", target.Account)
+		printPlanChecks(opts.Out, checks[t])
+		for _, check := range checks[t] {
+			if !check.OK() {
+				report.Accounts[t].Status = statusFailed
+				errs = append(errs, fmt.Errorf("account %s region %s does not match the plan", target.Account.ID, check.Region))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("plan %s is out of date, nothing was deleted: %w", opts.PlanFile, errors.Join(errs...))
+	}
+
+	if !opts.SkipPreflight {
+		reports := make([][]RegionPreflight, len(targets))
+		for t, target := range targets {
+			reports[t] = make([]RegionPreflight, len(target.Regions.Regions))
+		}
+		forEachAccountRegion(ctx, targets, opts.Concurrency, func(ctx context.Context, t, r int) {
+			planned := plan.Accounts[t].Regions[r]
+			reports[t][r] = preflightRegion(ctx, targets[t].NewClient(planned.Region), planned.regionPlan())
+		})
+		for t, target := range targets {
+			fmt.Fprintf(opts.Out, "Account %s\n", target.Account)
+			printPreflight(opts.Out, reports[t])
+			for _, regionReport := range reports[t] {
+				if !regionReport.OK() {
+					report.Accounts[t].Status = statusFailed
+					errs = append(errs, fmt.Errorf("account %s region %s failed preflight", target.Account.ID, regionReport.Region))
+				}
+			}
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("preflight failed, nothing was deleted: %w", errors.Join(errs...))
+		}
+	}
+
+	if !opts.Yes && hasPlannedVPCs(plan) {
+		confirmID := targets[0].Account.ID
+		if opts.Organization.Enabled {
+			if confirmID, err = callerAccountID(ctx, cfg); err != nil {
+				return err
+			}
+		}
+		caller := Account{ID: confirmID, Name: accountAlias(ctx, cfg)}
+		err := confirmApply(opts.Out, confirmID, confirmDelete, func(w io.Writer) {
+			fmt.Fprintf(w, "Plan %s made %s, run from account %s\n", opts.PlanFile, plan.CreatedAt.Format(time.RFC3339), caller)
+			printPlanFile(w, plan)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	takenAt := time.Now()
+	result := &SweepResult{Accounts: make([]AccountResult, len(targets))}
+	for t, target := range targets {
+		result.Accounts[t] = AccountResult{Account: target.Account, Result: &RunResult{Regions: make([]RegionResult, len(target.Regions.Regions))}}
+	}
+	forEachAccountRegion(ctx, targets, opts.Concurrency, func(ctx context.Context, t, r int) {
+		planned := plan.Accounts[t].Regions[r]
+		backup := newSnapshotWriter(opts.SnapshotDir, targets[t].Account.ID, takenAt)
+		result.Accounts[t].Result.Regions[r] = applyPlannedRegion(ctx, targets[t].NewClient(planned.Region), planned, backup)
+	})
+	printSweepSummary(opts.Out, result)
+	for t, account := range result.Accounts {
+		report.Accounts[t].addResult(account.Result)
+	}
+	return result.Err()
+}
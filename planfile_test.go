@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+func Test_writePlanFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	plans := []RegionPlan{{
+		Region: "eu-west-1",
+		VPCs: []VPCPlan{
+			{VpcID: "vpc-1", Subnets: []string{"subnet-1"}},
+			{VpcID: "vpc-2", SkipReason: "protected by VPC ID", Protected: true},
+		},
+	}, {
+		Region: "us-east-1",
+	}}
+	plan := PlanFile{
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Accounts:  []PlannedAccount{newPlannedAccount(Account{ID: "111111111111"}, plans)},
+	}
+	if err := writePlanFile(path, plan); err != nil {
+		t.Fatalf("writePlanFile() error = %v", err)
+	}
+
+	got, err := readPlanFile(path)
+	if err != nil {
+		t.Fatalf("readPlanFile() error = %v", err)
+	}
+	plan.Version = planFileVersion
+	if !reflect.DeepEqual(got, plan) {
+		t.Errorf("readPlanFile() = %+v, want %+v", got, plan)
+	}
+	if regions := got.Accounts[0].Regions; len(regions) != 1 || len(regions[0].VPCs) != 1 || regions[0].VPCs[0].VpcID != "vpc-1" {
+		t.Errorf("planned regions = %+v, want only vpc-1 in eu-west-1", regions)
+	}
+}
+
+func Test_checkPlannedVPC(t *testing.T) {
+	planned := VPCPlan{
+		VpcID:             "vpc-12345",
+		InternetGateways:  []string{"igw-12345"},
+		Subnets:           []string{"subnet-12345", "subnet-gone"},
+		NetworkInterfaces: []string{"eni-1"},
+	}
+
+	tests := []struct {
+		name       string
+		enis       []string
+		vpcErr     error
+		protection Protection
+		want       []string
+	}{
+		{
+			name: "unchanged",
+			enis: []string{"eni-1"},
+		},
+		{
+			name: "new network interface",
+			enis: []string{"eni-1", "eni-2"},
+			want: []string{"VPC vpc-12345 has a new network interface eni-2"},
+		},
+		{
+			name:       "now protected",
+			protection: Protection{VpcIDs: []string{"vpc-12345"}},
+			want:       []string{"VPC vpc-12345 is now protected by VPC ID"},
+		},
+		{
+			name:   "VPC deleted",
+			vpcErr: &smithy.GenericAPIError{Code: "InvalidVpcID.NotFound"},
+			want:   []string{"VPC vpc-12345 no longer exists"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := snapshotMock()
+			describeVpcs := client.describeVpcsFunc
+			client.describeVpcsFunc = func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+				if tt.vpcErr != nil {
+					return nil, tt.vpcErr
+				}
+				return describeVpcs(ctx, input, optFns...)
+			}
+			client.describeNetworkInterfacesFunc = func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+				var enis []types.NetworkInterface
+				for _, id := range tt.enis {
+					enis = append(enis, types.NetworkInterface{NetworkInterfaceId: aws.String(id)})
+				}
+				return &ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: enis}, nil
+			}
+
+			got, err := checkPlannedVPC(context.Background(), client, planned, tt.protection)
+			if err != nil {
+				t.Fatalf("checkPlannedVPC() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkPlannedVPC() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("new subnet", func(t *testing.T) {
+		client := snapshotMock()
+		client.describeSubnetsFunc = func(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+			return &ec2.DescribeSubnetsOutput{Subnets: []types.Subnet{{SubnetId: aws.String("subnet-12345")}, {SubnetId: aws.String("subnet-new")}}}, nil
+		}
+		client.describeNetworkInterfacesFunc = func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+			return &ec2.DescribeNetworkInterfacesOutput{}, nil
+		}
+
+		got, err := checkPlannedVPC(context.Background(), client, planned, Protection{})
+		if err != nil {
+			t.Fatalf("checkPlannedVPC() error = %v", err)
+		}
+		if want := []string{"VPC vpc-12345 has a new subnet subnet-new"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("checkPlannedVPC() = %v, want %v", got, want)
+		}
+	})
+}
+
+func Test_applyPlannedVPC(t *testing.T) {
+	var calls []string
+	client := &MockEC2Client{
+		detachInternetGatewayFunc: func(ctx context.Context, input *ec2.DetachInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DetachInternetGatewayOutput, error) {
+			calls = append(calls, "detach "+aws.ToString(input.InternetGatewayId))
+			return &ec2.DetachInternetGatewayOutput{}, nil
+		},
+		deleteInternetGatewayFunc: func(ctx context.Context, input *ec2.DeleteInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DeleteInternetGatewayOutput, error) {
+			calls = append(calls, "delete "+aws.ToString(input.InternetGatewayId))
+			return &ec2.DeleteInternetGatewayOutput{}, nil
+		},
+		deleteSubnetFunc: func(ctx context.Context, input *ec2.DeleteSubnetInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSubnetOutput, error) {
+			calls = append(calls, "delete "+aws.ToString(input.SubnetId))
+			return nil, &smithy.GenericAPIError{Code: "InvalidSubnetID.NotFound"}
+		},
+		deleteVpcFunc: func(ctx context.Context, input *ec2.DeleteVpcInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVpcOutput, error) {
+			calls = append(calls, "delete "+aws.ToString(input.VpcId))
+			return &ec2.DeleteVpcOutput{}, nil
+		},
+	}
+	plan := VPCPlan{
+		VpcID:            "vpc-1",
+		InternetGateways: []string{"igw-1"},
+		Subnets:          []string{"subnet-gone"},
+		Steps: []PlanStep{
+			{Action: actionDetach, Type: resourceInternetGateway, ID: "igw-1"},
+			{Action: actionDelete, Type: resourceInternetGateway, ID: "igw-1"},
+			{Action: actionDelete, Type: resourceSubnet, ID: "subnet-gone"},
+			{Action: actionDelete, Type: resourceVPC, ID: "vpc-1"},
+		},
+	}
+
+	result := applyPlannedVPC(context.Background(), client, "eu-west-1", plan)
+	if result.Err != nil {
+		t.Fatalf("applyPlannedVPC() error = %v", result.Err)
+	}
+	if want := []string{"detach igw-1", "delete igw-1", "delete subnet-gone", "delete vpc-1"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if !result.Deleted() {
+		t.Error("applyPlannedVPC() did not record the VPC as deleted")
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+// PermissionCheck is the outcome of a single DryRun call
+type PermissionCheck struct {
+	Action     string
+	ResourceID string
+	Allowed    bool
+	// Err is set when the dry run failed for a reason other than permissions
+	Err error
+}
+
+// RegionPreflight holds every permission check made in a region
+type RegionPreflight struct {
+	Region string
+	Checks []PermissionCheck
+	Err    error
+}
+
+// OK reports whether every mutating call in the region is permitted
+func (p RegionPreflight) OK() bool {
+	if p.Err != nil {
+		return false
+	}
+	for _, check := range p.Checks {
+		if !check.Allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// Interpret the error from a DryRun call. EC2 answers a permitted dry run with
+// DryRunOperation and a forbidden one with UnauthorizedOperation.
+func dryRunResult(action, resourceID string, err error) PermissionCheck {
+	check := PermissionCheck{Action: action, ResourceID: resourceID}
+
+	var apiErr smithy.APIError
+	switch {
+	case err == nil:
+		// A dry run should never succeed, but if it does nothing was blocked
+		check.Allowed = true
+	case errors.As(err, &apiErr) && apiErr.ErrorCode() == "DryRunOperation":
+		check.Allowed = true
+	case errors.As(err, &apiErr) && apiErr.ErrorCode() == "UnauthorizedOperation":
+		check.Err = fmt.Errorf("not authorized to call %s on %s", action, resourceID)
+	default:
+		check.Err = fmt.Errorf("dry run of %s on %s failed: %w", action, resourceID, err)
+	}
+	return check
+}
+
+// Call each mutating EC2 method with DryRun set, once per action per VPC,
+// against resources taken from the region's plan
+func preflightRegion(ctx context.Context, client EC2API, plan RegionPlan) RegionPreflight {
+	report := RegionPreflight{Region: plan.Region, Err: plan.Err}
+	if plan.Err != nil {
+		return report
+	}
+
+	for _, vpc := range plan.VPCs {
+		if vpc.SkipReason != "" {
+			continue
+		}
+
+		if len(vpc.VpcEndpoints) > 0 {
+			out, err := client.DeleteVpcEndpoints(ctx, &ec2.DeleteVpcEndpointsInput{
+				DryRun:         aws.Bool(true),
+				VpcEndpointIds: vpc.VpcEndpoints[:1],
+			})
+			if err == nil && len(out.Unsuccessful) > 0 && out.Unsuccessful[0].Error != nil {
+				err = &smithy.GenericAPIError{Code: aws.ToString(out.Unsuccessful[0].Error.Code), Message: aws.ToString(out.Unsuccessful[0].Error.Message)}
+			}
+			report.Checks = append(report.Checks, dryRunResult("DeleteVpcEndpoints", vpc.VpcEndpoints[0], err))
+		}
+
+		if len(vpc.NatGateways) > 0 {
+			_, err := client.DeleteNatGateway(ctx, &ec2.DeleteNatGatewayInput{
+				DryRun:       aws.Bool(true),
+				NatGatewayId: aws.String(vpc.NatGateways[0]),
+			})
+			report.Checks = append(report.Checks, dryRunResult("DeleteNatGateway", vpc.NatGateways[0], err))
+		}
+
+		if len(vpc.ElasticIPs) > 0 {
+			_, err := client.ReleaseAddress(ctx, &ec2.ReleaseAddressInput{
+				DryRun:       aws.Bool(true),
+				AllocationId: aws.String(vpc.ElasticIPs[0]),
+			})
+			report.Checks = append(report.Checks, dryRunResult("ReleaseAddress", vpc.ElasticIPs[0], err))
+		}
+
+		for _, step := range vpc.Steps {
+			if step.Type != resourceNetworkInterface || step.Action != actionDelete {
+				continue
+			}
+			_, err := client.DeleteNetworkInterface(ctx, &ec2.DeleteNetworkInterfaceInput{
+				DryRun:             aws.Bool(true),
+				NetworkInterfaceId: aws.String(step.ID),
+			})
+			report.Checks = append(report.Checks, dryRunResult("DeleteNetworkInterface", step.ID, err))
+			break
+		}
+
+		if len(vpc.InternetGateways) > 0 {
+			igwID := vpc.InternetGateways[0]
+			_, err := client.DetachInternetGateway(ctx, &ec2.DetachInternetGatewayInput{
+				DryRun:            aws.Bool(true),
+				InternetGatewayId: aws.String(igwID),
+				VpcId:             aws.String(vpc.VpcID),
+			})
+			report.Checks = append(report.Checks, dryRunResult("DetachInternetGateway", igwID, err))
+
+			_, err = client.DeleteInternetGateway(ctx, &ec2.DeleteInternetGatewayInput{
+				DryRun:            aws.Bool(true),
+				InternetGatewayId: aws.String(igwID),
+			})
+			report.Checks = append(report.Checks, dryRunResult("DeleteInternetGateway", igwID, err))
+		}
+
+		if len(vpc.EgressOnlyInternetGateways) > 0 {
+			_, err := client.DeleteEgressOnlyInternetGateway(ctx, &ec2.DeleteEgressOnlyInternetGatewayInput{
+				DryRun:                      aws.Bool(true),
+				EgressOnlyInternetGatewayId: aws.String(vpc.EgressOnlyInternetGateways[0]),
+			})
+			report.Checks = append(report.Checks, dryRunResult("DeleteEgressOnlyInternetGateway", vpc.EgressOnlyInternetGateways[0], err))
+		}
+
+		if len(vpc.Subnets) > 0 {
+			_, err := client.DeleteSubnet(ctx, &ec2.DeleteSubnetInput{
+				DryRun:   aws.Bool(true),
+				SubnetId: aws.String(vpc.Subnets[0]),
+			})
+			report.Checks = append(report.Checks, dryRunResult("DeleteSubnet", vpc.Subnets[0], err))
+		}
+
+		if len(vpc.RouteTables) > 0 {
+			_, err := client.DeleteRouteTable(ctx, &ec2.DeleteRouteTableInput{
+				DryRun:       aws.Bool(true),
+				RouteTableId: aws.String(vpc.RouteTables[0]),
+			})
+			report.Checks = append(report.Checks, dryRunResult("DeleteRouteTable", vpc.RouteTables[0], err))
+		}
+
+		if len(vpc.NetworkACLs) > 0 {
+			_, err := client.DeleteNetworkAcl(ctx, &ec2.DeleteNetworkAclInput{
+				DryRun:       aws.Bool(true),
+				NetworkAclId: aws.String(vpc.NetworkACLs[0]),
+			})
+			report.Checks = append(report.Checks, dryRunResult("DeleteNetworkAcl", vpc.NetworkACLs[0], err))
+		}
+
+		for _, step := range vpc.Steps {
+			if step.Type != resourceSecurityGroup || step.Action != actionRevoke {
+				continue
+			}
+			// A rule referring to the group itself stands in for the real
+			// ones, DryRun only checks the caller may revoke rules
+			permissions := []types.IpPermission{{
+				IpProtocol:       aws.String("-1"),
+				UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: aws.String(step.ID)}},
+			}}
+			_, err := client.RevokeSecurityGroupIngress(ctx, &ec2.RevokeSecurityGroupIngressInput{
+				DryRun:        aws.Bool(true),
+				GroupId:       aws.String(step.ID),
+				IpPermissions: permissions,
+			})
+			report.Checks = append(report.Checks, dryRunResult("RevokeSecurityGroupIngress", step.ID, err))
+
+			_, err = client.RevokeSecurityGroupEgress(ctx, &ec2.RevokeSecurityGroupEgressInput{
+				DryRun:        aws.Bool(true),
+				GroupId:       aws.String(step.ID),
+				IpPermissions: permissions,
+			})
+			report.Checks = append(report.Checks, dryRunResult("RevokeSecurityGroupEgress", step.ID, err))
+			break
+		}
+
+		if len(vpc.SecurityGroups) > 0 {
+			_, err := client.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{
+				DryRun:  aws.Bool(true),
+				GroupId: aws.String(vpc.SecurityGroups[0]),
+			})
+			report.Checks = append(report.Checks, dryRunResult("DeleteSecurityGroup", vpc.SecurityGroups[0], err))
+		}
+
+		_, err := client.DeleteVpc(ctx, &ec2.DeleteVpcInput{
+			DryRun: aws.Bool(true),
+			VpcId:  aws.String(vpc.VpcID),
+		})
+		report.Checks = append(report.Checks, dryRunResult("DeleteVpc", vpc.VpcID, err))
+	}
+
+	return report
+}
+
+// PreflightAllDefaultVPCs checks, without changing anything, that every
+// Delete, Detach and Revoke call DeleteAllDefaultVPCs will make is
+// permitted. At most concurrency regions are checked at once.
+func PreflightAllDefaultVPCs(ctx context.Context, regions []string, newClient ClientFactory, protection Protection, cleanup CleanupOptions, concurrency int) ([]RegionPreflight, error) {
+	reports := make([]RegionPreflight, len(regions))
+
+	runBounded(len(regions), concurrency, func(i int) {
+		client := newClient(regions[i])
+		reports[i] = preflightRegion(ctx, client, planRegion(ctx, client, regions[i], protection, cleanup))
+	})
+
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Region < reports[j].Region })
+
+	var errs []error
+	for _, report := range reports {
+		if report.Err != nil {
+			errs = append(errs, report.Err)
+		}
+		for _, check := range report.Checks {
+			if check.Err != nil {
+				errs = append(errs, fmt.Errorf("region %s: %w", report.Region, check.Err))
+			}
+		}
+	}
+	return reports, errors.Join(errs...)
+}
+
+// Write the preflight results per region
+func printPreflight(w io.Writer, reports []RegionPreflight) {
+	for _, report := range reports {
+		status := "ok"
+		if !report.OK() {
+			status = "FAILED"
+		}
+		fmt.Fprintf(w, "Preflight %s: %s\n", report.Region, status)
+		if report.Err != nil {
+			fmt.Fprintf(w, "  Error: %v\n", report.Err)
+		}
+		for _, check := range report.Checks {
+			if check.Allowed {
+				fmt.Fprintf(w, "  allowed: %s %s\n", check.Action, check.ResourceID)
+			} else {
+				fmt.Fprintf(w, "  failed:  %s %s: %v\n", check.Action, check.ResourceID, check.Err)
+			}
+		}
+	}
+}
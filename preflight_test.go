@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/smithy-go"
+)
+
+func Test_dryRunResult(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantAllowed bool
+	}{
+		{
+			name:        "DryRunOperation means allowed",
+			err:         &smithy.GenericAPIError{Code: "DryRunOperation"},
+			wantAllowed: true,
+		},
+		{
+			name:        "UnauthorizedOperation means denied",
+			err:         &smithy.GenericAPIError{Code: "UnauthorizedOperation"},
+			wantAllowed: false,
+		},
+		{
+			name:        "wrapped DryRunOperation",
+			err:         fmt.Errorf("operation error EC2: DeleteVpc: %w", &smithy.GenericAPIError{Code: "DryRunOperation"}),
+			wantAllowed: true,
+		},
+		{
+			name:        "unrelated error",
+			err:         fmt.Errorf("connection reset"),
+			wantAllowed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dryRunResult("DeleteVpc", "vpc-12345", tt.err)
+			if got.Allowed != tt.wantAllowed {
+				t.Errorf("dryRunResult() allowed = %v, want %v", got.Allowed, tt.wantAllowed)
+			}
+			if got.Allowed == (got.Err != nil) {
+				t.Errorf("dryRunResult() allowed = %v but err = %v", got.Allowed, got.Err)
+			}
+		})
+	}
+}
+
+func Test_preflightRegion(t *testing.T) {
+	allowed := &smithy.GenericAPIError{Code: "DryRunOperation"}
+	denied := &smithy.GenericAPIError{Code: "UnauthorizedOperation"}
+
+	tests := []struct {
+		name       string
+		client     EC2API
+		plan       RegionPlan
+		wantChecks int
+		wantOK     bool
+	}{
+		{
+			name: "all actions allowed",
+			client: &MockEC2Client{
+				detachInternetGatewayFunc: func(ctx context.Context, input *ec2.DetachInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DetachInternetGatewayOutput, error) {
+					return nil, allowed
+				},
+				deleteInternetGatewayFunc: func(ctx context.Context, input *ec2.DeleteInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DeleteInternetGatewayOutput, error) {
+					return nil, allowed
+				},
+				deleteSubnetFunc: func(ctx context.Context, input *ec2.DeleteSubnetInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSubnetOutput, error) {
+					if !*input.DryRun {
+						t.Fatalf("DeleteSubnet called without DryRun")
+					}
+					return nil, allowed
+				},
+				deleteVpcFunc: func(ctx context.Context, input *ec2.DeleteVpcInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVpcOutput, error) {
+					return nil, allowed
+				},
+			},
+			plan: RegionPlan{
+				Region: "us-east-1",
+				VPCs: []VPCPlan{
+					{
+						VpcID:            "vpc-12345",
+						InternetGateways: []string{"igw-12345"},
+						Subnets:          []string{"subnet-1", "subnet-2"},
+					},
+				},
+			},
+			wantChecks: 4,
+			wantOK:     true,
+		},
+		{
+			name: "delete VPC denied",
+			client: &MockEC2Client{
+				deleteVpcFunc: func(ctx context.Context, input *ec2.DeleteVpcInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVpcOutput, error) {
+					return nil, denied
+				},
+			},
+			plan: RegionPlan{
+				Region: "us-east-1",
+				VPCs:   []VPCPlan{{VpcID: "vpc-12345"}},
+			},
+			wantChecks: 1,
+			wantOK:     false,
+		},
+		{
+			name:   "no default VPCs",
+			client: &MockEC2Client{},
+			plan: RegionPlan{
+				Region: "us-east-1",
+			},
+			wantChecks: 0,
+			wantOK:     true,
+		},
+		{
+			name:   "planning failed",
+			client: &MockEC2Client{},
+			plan: RegionPlan{
+				Region: "us-east-1",
+				Err:    fmt.Errorf("failed to fetch VPCs"),
+			},
+			wantChecks: 0,
+			wantOK:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := preflightRegion(context.Background(), tt.client, tt.plan)
+			if len(got.Checks) != tt.wantChecks {
+				t.Errorf("preflightRegion() made %d checks, want %d", len(got.Checks), tt.wantChecks)
+			}
+			if got.OK() != tt.wantOK {
+				t.Errorf("preflightRegion() OK = %v, want %v", got.OK(), tt.wantOK)
+			}
+		})
+	}
+}
+
+func Test_preflightRegion_revoke(t *testing.T) {
+	ctx := context.Background()
+	for _, denied := range []string{"RevokeSecurityGroupIngress", "RevokeSecurityGroupEgress"} {
+		t.Run(denied, func(t *testing.T) {
+			fake := NewFakeEC2()
+			fake.AddRegion("us-east-1", "us-east-1a")
+			vpcID := fake.AddDefaultVPC("us-east-1")
+			web := fake.AddSecurityGroup("us-east-1", vpcID, "web")
+			fake.AddSecurityGroupReference("us-east-1", fake.DefaultSecurityGroup("us-east-1", vpcID), web)
+			fake.Deny(denied)
+
+			client := fake.Client("us-east-1")
+			report := preflightRegion(ctx, client, planRegion(ctx, client, "us-east-1", Protection{}, CleanupOptions{}))
+			if report.OK() {
+				t.Errorf("preflightRegion() = %+v, want %s to fail", report, denied)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Protection lists the default VPCs that must never be deleted, by ID or by
+// a tag. A VPC matching any entry is protected.
+type Protection struct {
+	VpcIDs []string
+	Tags   []types.Tag
+}
+
+// ProtectedVPC is a default VPC left alone because Protection matched it
+type ProtectedVPC struct {
+	VpcID  string
+	Reason string
+}
+
+// parseProtectTags turns key=value pairs into tags. The value may be empty
+// but the = may not be left out.
+func parseProtectTags(pairs []string) ([]types.Tag, error) {
+	var tags []types.Tag
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("--protect-tag %q must be key=value", pair)
+		}
+		tags = append(tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return tags, nil
+}
+
+// reason explains why vpc is protected, or returns "" if it isn't
+func (p Protection) reason(vpc types.Vpc) string {
+	if slices.Contains(p.VpcIDs, aws.ToString(vpc.VpcId)) {
+		return "protected by VPC ID"
+	}
+	for _, want := range p.Tags {
+		for _, tag := range vpc.Tags {
+			if aws.ToString(tag.Key) == aws.ToString(want.Key) && aws.ToString(tag.Value) == aws.ToString(want.Value) {
+				return fmt.Sprintf("protected by tag %s=%s", aws.ToString(tag.Key), aws.ToString(tag.Value))
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func Test_parseProtectTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []string
+		want    []types.Tag
+		wantErr bool
+	}{
+		{
+			name:  "key and value",
+			pairs: []string{"keep=true", "team=payments"},
+			want: []types.Tag{
+				{Key: aws.String("keep"), Value: aws.String("true")},
+				{Key: aws.String("team"), Value: aws.String("payments")},
+			},
+		},
+		{
+			name:  "empty value",
+			pairs: []string{"keep="},
+			want:  []types.Tag{{Key: aws.String("keep"), Value: aws.String("")}},
+		},
+		{
+			name:    "missing =",
+			pairs:   []string{"keep"},
+			wantErr: true,
+		},
+		{
+			name:    "missing key",
+			pairs:   []string{"=true"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProtectTags(tt.pairs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseProtectTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseProtectTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getDefaultVPCs_protection(t *testing.T) {
+	client := &MockEC2Client{
+		describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+			return &ec2.DescribeVpcsOutput{
+				Vpcs: []types.Vpc{
+					{VpcId: aws.String("vpc-listed"), IsDefault: aws.Bool(true)},
+					{VpcId: aws.String("vpc-tagged"), IsDefault: aws.Bool(true), Tags: []types.Tag{{Key: aws.String("keep"), Value: aws.String("true")}}},
+					{VpcId: aws.String("vpc-other-value"), IsDefault: aws.Bool(true), Tags: []types.Tag{{Key: aws.String("keep"), Value: aws.String("false")}}},
+					{VpcId: aws.String("vpc-custom"), IsDefault: aws.Bool(false), Tags: []types.Tag{{Key: aws.String("keep"), Value: aws.String("true")}}},
+				},
+			}, nil
+		},
+	}
+	protection := Protection{
+		VpcIDs: []string{"vpc-listed"},
+		Tags:   []types.Tag{{Key: aws.String("keep"), Value: aws.String("true")}},
+	}
+
+	vpcs, protected, err := getDefaultVPCs(context.Background(), client, protection)
+	if err != nil {
+		t.Fatalf("getDefaultVPCs() error = %v", err)
+	}
+	if want := []string{"vpc-other-value"}; !reflect.DeepEqual(vpcs, want) {
+		t.Errorf("getDefaultVPCs() = %v, want %v", vpcs, want)
+	}
+	wantProtected := []ProtectedVPC{
+		{VpcID: "vpc-listed", Reason: "protected by VPC ID"},
+		{VpcID: "vpc-tagged", Reason: "protected by tag keep=true"},
+	}
+	if !reflect.DeepEqual(protected, wantProtected) {
+		t.Errorf("getDefaultVPCs() protected = %v, want %v", protected, wantProtected)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/smithy-go/middleware"
+	"golang.org/x/time/rate"
+)
+
+// RateLimiter is a token bucket shared by every AWS client in a run. It
+// also counts the requests it lets through so the achieved rate can be
+// reported at the end.
+type RateLimiter struct {
+	limiter  *rate.Limiter
+	requests atomic.Int64
+	started  time.Time
+}
+
+// NewRateLimiter allows perSecond requests on average with bursts of up to
+// burst. A perSecond of zero or less turns limiting off but still counts.
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	limit := rate.Limit(perSecond)
+	if perSecond <= 0 {
+		limit = rate.Inf
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{limiter: rate.NewLimiter(limit, burst), started: time.Now()}
+}
+
+// Wait blocks until the bucket has a token for one request
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.requests.Add(1)
+	return l.limiter.Wait(ctx)
+}
+
+// Requests returns how many requests were made and the average rate since
+// the limiter was created
+func (l *RateLimiter) Requests() (int64, float64) {
+	n := l.requests.Load()
+	elapsed := time.Since(l.started).Seconds()
+	if elapsed <= 0 {
+		return n, 0
+	}
+	return n, float64(n) / elapsed
+}
+
+// apiOption is the middleware that adds the limiter to an AWS client's
+// stack. It runs after the SDK's retry middleware so every attempt takes a
+// token.
+func (l *RateLimiter) apiOption() func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("RateLimiter", func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			if err := l.Wait(ctx); err != nil {
+				return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+			}
+			return next.HandleFinalize(ctx, in)
+		}), middleware.After)
+	}
+}
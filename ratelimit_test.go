@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsretry "github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+// stubHTTPClient answers every request with the same status and body
+type stubHTTPClient struct {
+	status int
+	body   string
+}
+
+func (c stubHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: c.status,
+		Header:     http.Header{"Content-Type": []string{"text/xml"}},
+		Body:       io.NopCloser(strings.NewReader(c.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	limiter := NewRateLimiter(100, 1)
+	start := time.Now()
+	for range 5 {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+	// The first request uses the burst, the next four wait 10ms each
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("Wait() let 5 requests through in %v, want at least 40ms", elapsed)
+	}
+	if n, perSecond := limiter.Requests(); n != 5 || perSecond <= 0 {
+		t.Errorf("Requests() = %d, %v", n, perSecond)
+	}
+}
+
+func TestRateLimiter_apiOption(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		wantCalls int64
+	}{
+		{
+			name:      "one request",
+			status:    http.StatusOK,
+			body:      `<DescribeRegionsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><requestId>1</requestId><regionInfo/></DescribeRegionsResponse>`,
+			wantCalls: 1,
+		},
+		{
+			name:      "every SDK retry takes a token",
+			status:    http.StatusServiceUnavailable,
+			body:      `<Response><Errors><Error><Code>Unavailable</Code><Message>try again</Message></Error></Errors><RequestID>1</RequestID></Response>`,
+			wantCalls: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimiter(0, 1)
+			client := ec2.New(ec2.Options{
+				Region:      "us-east-1",
+				Credentials: aws.AnonymousCredentials{},
+				HTTPClient:  stubHTTPClient{status: tt.status, body: tt.body},
+				Retryer:     awsretry.AddWithMaxBackoffDelay(awsretry.NewStandard(), time.Millisecond),
+			}, func(o *ec2.Options) {
+				o.APIOptions = append(o.APIOptions, limiter.apiOption())
+			})
+
+			client.DescribeRegions(context.Background(), &ec2.DescribeRegionsInput{})
+			if n, _ := limiter.Requests(); n != tt.wantCalls {
+				t.Errorf("limiter saw %d requests, want %d", n, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Region is an EC2 region along with the account's opt-in status for it
+type Region struct {
+	Name        string
+	OptInStatus string
+}
+
+// Enabled reports whether the account can use the region. Regions that need
+// opting in are only usable once the status is "opted-in".
+func (r Region) Enabled() bool {
+	switch r.OptInStatus {
+	case "opted-in", "opt-in-not-required":
+		return true
+	}
+	return false
+}
+
+// SkippedRegion is a region that was deliberately not processed
+type SkippedRegion struct {
+	Region string `json:"region"`
+	Reason string `json:"reason"`
+}
+
+// Split regions into the names that can be processed and the ones that are
+// skipped because the account has not opted in to them
+func partitionRegions(regions []Region) ([]string, []SkippedRegion) {
+	enabled := []string{}
+	var skipped []SkippedRegion
+	for _, region := range regions {
+		if region.Enabled() {
+			enabled = append(enabled, region.Name)
+			continue
+		}
+		skipped = append(skipped, SkippedRegion{
+			Region: region.Name,
+			Reason: fmt.Sprintf("opt-in status is %s", region.OptInStatus),
+		})
+	}
+	return enabled, skipped
+}
+
+// Write the regions that will be processed, with their opt-in status, and
+// the ones skipped, with the reason
+func printRegions(w io.Writer, regions regionSet) {
+	status := map[string]string{}
+	for _, region := range regions.All {
+		status[region.Name] = region.OptInStatus
+	}
+	for _, name := range regions.Regions {
+		fmt.Fprintf(w, "Region %s: %s, processing\n", name, status[name])
+	}
+	for _, skipped := range regions.Skipped {
+		fmt.Fprintf(w, "Region %s: skipping, %s\n", skipped.Region, skipped.Reason)
+	}
+}
+
+// List the regions removed by filterRegions
+func regionsFilteredOut(before, after []string) []SkippedRegion {
+	var skipped []SkippedRegion
+	for _, region := range before {
+		if !slices.Contains(after, region) {
+			skipped = append(skipped, SkippedRegion{Region: region, Reason: "excluded by region filters"})
+		}
+	}
+	return skipped
+}
+
+// Split a comma separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Report whether name matches any of the glob patterns
+func matchesAny(name string, patterns []string) (bool, error) {
+	for _, pattern := range patterns {
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("invalid region pattern %q: %w", pattern, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Keep the regions matching include (or every region when include is empty)
+// and drop any matching exclude. Patterns are globs such as "eu-*".
+func filterRegions(regions, include, exclude []string) ([]string, error) {
+	filtered := []string{}
+	for _, region := range regions {
+		if len(include) > 0 {
+			ok, err := matchesAny(region, include)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		excluded, err := matchesAny(region, exclude)
+		if err != nil {
+			return nil, err
+		}
+		if excluded {
+			continue
+		}
+
+		filtered = append(filtered, region)
+	}
+	return filtered, nil
+}
+
+// RegionSelection picks the regions a run covers
+type RegionSelection struct {
+	AllRegions bool
+	Include    []string
+	Exclude    []string
+}
+
+// regionSet is the outcome of selecting regions for an account
+type regionSet struct {
+	All     []Region
+	Regions []string
+	Skipped []SkippedRegion
+}
+
+// Look up the account's regions and apply the opt-in status and filters
+func selectRegions(ctx context.Context, client EC2API, selection RegionSelection) (regionSet, error) {
+	all, err := getRegions(ctx, client, selection.AllRegions)
+	if err != nil {
+		return regionSet{}, fmt.Errorf("unable to describe regions: %w", err)
+	}
+
+	enabled, skipped := partitionRegions(all)
+	regions, err := filterRegions(enabled, selection.Include, selection.Exclude)
+	if err != nil {
+		return regionSet{}, fmt.Errorf("unable to filter regions: %w", err)
+	}
+	skipped = append(skipped, regionsFilteredOut(enabled, regions)...)
+
+	return regionSet{All: all, Regions: regions, Skipped: skipped}, nil
+}
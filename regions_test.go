@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func Test_filterRegions(t *testing.T) {
+	regions := []string{"eu-central-1", "eu-west-1", "us-east-1", "us-west-2"}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "no filters keeps every region",
+			want: regions,
+		},
+		{
+			name:    "include glob",
+			include: []string{"eu-*"},
+			want:    []string{"eu-central-1", "eu-west-1"},
+		},
+		{
+			name:    "exclude exact region",
+			exclude: []string{"us-east-1"},
+			want:    []string{"eu-central-1", "eu-west-1", "us-west-2"},
+		},
+		{
+			name:    "exclude wins over include",
+			include: []string{"us-*"},
+			exclude: []string{"us-west-*"},
+			want:    []string{"us-east-1"},
+		},
+		{
+			name:    "nothing matches",
+			include: []string{"ap-*"},
+			want:    []string{},
+		},
+		{
+			name:    "invalid pattern",
+			include: []string{"eu-[*"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filterRegions(regions, tt.include, tt.exclude)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("filterRegions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterRegions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_splitList(t *testing.T) {
+	got := splitList(" us-east-1, eu-*,,")
+	if want := []string{"us-east-1", "eu-*"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("splitList() = %v, want %v", got, want)
+	}
+	if got := splitList(""); got != nil {
+		t.Errorf("splitList(\"\") = %v, want nil", got)
+	}
+}
+
+func Test_partitionRegions(t *testing.T) {
+	regions := []Region{
+		{Name: "us-east-1", OptInStatus: "opt-in-not-required"},
+		{Name: "af-south-1", OptInStatus: "opted-in"},
+		{Name: "ap-east-1", OptInStatus: "not-opted-in"},
+	}
+
+	enabled, skipped := partitionRegions(regions)
+	if want := []string{"us-east-1", "af-south-1"}; !reflect.DeepEqual(enabled, want) {
+		t.Errorf("partitionRegions() enabled = %v, want %v", enabled, want)
+	}
+	wantSkipped := []SkippedRegion{{Region: "ap-east-1", Reason: "opt-in status is not-opted-in"}}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("partitionRegions() skipped = %v, want %v", skipped, wantSkipped)
+	}
+}
+
+func Test_regionsFilteredOut(t *testing.T) {
+	got := regionsFilteredOut([]string{"us-east-1", "us-west-1", "eu-west-1"}, []string{"us-east-1"})
+	want := []SkippedRegion{
+		{Region: "us-west-1", Reason: "excluded by region filters"},
+		{Region: "eu-west-1", Reason: "excluded by region filters"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("regionsFilteredOut() = %v, want %v", got, want)
+	}
+}
+
+func Test_printRegions(t *testing.T) {
+	regions := regionSet{
+		All: []Region{
+			{Name: "us-east-1", OptInStatus: "opt-in-not-required"},
+			{Name: "us-west-1", OptInStatus: "opt-in-not-required"},
+			{Name: "ap-east-1", OptInStatus: "not-opted-in"},
+		},
+		Regions: []string{"us-east-1"},
+		Skipped: []SkippedRegion{
+			{Region: "ap-east-1", Reason: "opt-in status is not-opted-in"},
+			{Region: "us-west-1", Reason: "excluded by region filters"},
+		},
+	}
+
+	var out bytes.Buffer
+	printRegions(&out, regions)
+	want := "Region us-east-1: opt-in-not-required, processing\n" +
+		"Region ap-east-1: skipping, opt-in status is not-opted-in\n" +
+		"Region us-west-1: skipping, excluded by region filters\n"
+	if got := out.String(); got != want {
+		t.Errorf("printRegions() = %q, want %q", got, want)
+	}
+}
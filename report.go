@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Report formats accepted by --report
+const (
+	reportText = "text"
+	reportJSON = "json"
+)
+
+// Statuses used throughout the JSON report
+const (
+	statusOK            = "ok"
+	statusFailed        = "failed"
+	statusSkipped       = "skipped"
+	statusProtected     = "protected"
+	statusDeleted       = "deleted"
+	statusPlanned       = "planned"
+	statusFound         = "found"
+	statusNoDefaultVPCs = "no-default-vpcs"
+)
+
+// Report is the machine readable record of a run written with --report json
+type Report struct {
+	Command    string          `json:"command"`
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt time.Time       `json:"finishedAt"`
+	DryRun     bool            `json:"dryRun"`
+	Status     string          `json:"status"`
+	Error      string          `json:"error,omitempty"`
+	Accounts   []AccountReport `json:"accounts"`
+
+	APIRequests          int64   `json:"apiRequests"`
+	APIRequestsPerSecond float64 `json:"apiRequestsPerSecond"`
+}
+
+// AccountReport is everything done in one account
+type AccountReport struct {
+	AccountID      string          `json:"accountId"`
+	AccountName    string          `json:"accountName,omitempty"`
+	Status         string          `json:"status"`
+	Error          string          `json:"error,omitempty"`
+	Regions        []RegionReport  `json:"regions"`
+	SkippedRegions []SkippedRegion `json:"skippedRegions"`
+}
+
+// RegionReport is everything done in one region. Plan is only set by plan.
+type RegionReport struct {
+	Region       string      `json:"region"`
+	Status       string      `json:"status"`
+	Error        string      `json:"error,omitempty"`
+	SnapshotPath string      `json:"snapshotPath,omitempty"`
+	VPCs         []VPCReport `json:"vpcs,omitempty"`
+	Plan         []VPCPlan   `json:"plan,omitempty"`
+}
+
+// VPCReport is everything done to one default VPC
+type VPCReport struct {
+	VpcID      string           `json:"vpcId"`
+	Status     string           `json:"status"`
+	SkipReason string           `json:"skipReason,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Resources  []ResourceReport `json:"resources"`
+}
+
+// ResourceReport is a single call made against a resource. Rules lists the
+// security group rules a revoke removed.
+type ResourceReport struct {
+	Type   string   `json:"type"`
+	ID     string   `json:"id"`
+	Action string   `json:"action"`
+	Result string   `json:"result"`
+	Rules  []string `json:"rules,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func statusOf(err error) string {
+	if err != nil {
+		return statusFailed
+	}
+	return statusOK
+}
+
+func newVPCReport(vpc VPCResult) VPCReport {
+	report := VPCReport{
+		VpcID:      vpc.VpcID,
+		SkipReason: vpc.SkipReason,
+		Error:      errorString(vpc.Err),
+		Resources:  []ResourceReport{},
+	}
+	switch {
+	case vpc.Protected:
+		report.Status = statusProtected
+	case vpc.SkipReason != "":
+		report.Status = statusSkipped
+	case vpc.Deleted():
+		report.Status = statusDeleted
+	default:
+		report.Status = statusFailed
+	}
+	for _, r := range vpc.Resources {
+		report.Resources = append(report.Resources, ResourceReport{
+			Type:   r.Type,
+			ID:     r.ID,
+			Action: r.Action,
+			Result: statusOf(r.Err),
+			Rules:  r.Rules,
+			Error:  errorString(r.Err),
+		})
+	}
+	return report
+}
+
+func newRegionReport(region RegionResult) RegionReport {
+	report := RegionReport{
+		Region:       region.Region,
+		Status:       statusOK,
+		Error:        errorString(region.Err),
+		SnapshotPath: region.SnapshotPath,
+	}
+	switch {
+	case region.Failed():
+		report.Status = statusFailed
+	case len(region.VPCs) == 0:
+		report.Status = statusNoDefaultVPCs
+	}
+	for _, vpc := range region.VPCs {
+		report.VPCs = append(report.VPCs, newVPCReport(vpc))
+	}
+	return report
+}
+
+func newPlanReport(plan RegionPlan) RegionReport {
+	report := RegionReport{
+		Region: plan.Region,
+		Status: statusPlanned,
+		Error:  errorString(plan.Err),
+		Plan:   plan.VPCs,
+	}
+	switch {
+	case plan.Err != nil:
+		report.Status = statusFailed
+	case len(plan.VPCs) == 0:
+		report.Status = statusNoDefaultVPCs
+	}
+	return report
+}
+
+// newInventoryReport records the default VPCs list or verify found. With
+// verify, a region that still has unprotected default VPCs has failed.
+func newInventoryReport(inventory RegionInventory, verify bool) RegionReport {
+	report := RegionReport{
+		Region: inventory.Region,
+		Status: statusOK,
+		Error:  errorString(inventory.Err),
+	}
+	switch {
+	case inventory.Err != nil:
+		report.Status = statusFailed
+	case verify && len(inventory.VPCs) > 0:
+		report.Status = statusFailed
+		report.Error = errorString(inventory.Remaining())
+	case len(inventory.VPCs) == 0 && len(inventory.Protected) == 0:
+		report.Status = statusNoDefaultVPCs
+	}
+	for _, vpcID := range inventory.VPCs {
+		report.VPCs = append(report.VPCs, VPCReport{VpcID: vpcID, Status: statusFound, Resources: []ResourceReport{}})
+	}
+	for _, protected := range inventory.Protected {
+		report.VPCs = append(report.VPCs, VPCReport{VpcID: protected.VpcID, Status: statusProtected, SkipReason: protected.Reason, Resources: []ResourceReport{}})
+	}
+	return report
+}
+
+// newAccountReport starts the report for an account. Err is set when the
+// account could not be processed at all.
+func newAccountReport(account Account, skipped []SkippedRegion, err error) AccountReport {
+	if skipped == nil {
+		skipped = []SkippedRegion{}
+	}
+	return AccountReport{
+		AccountID:      account.ID,
+		AccountName:    account.Name,
+		Status:         statusOf(err),
+		Error:          errorString(err),
+		Regions:        []RegionReport{},
+		SkippedRegions: skipped,
+	}
+}
+
+// addResult records the outcome of deleting the account's default VPCs
+func (a *AccountReport) addResult(result *RunResult) {
+	for _, region := range result.Regions {
+		a.Regions = append(a.Regions, newRegionReport(region))
+	}
+	if result.Err() != nil {
+		a.Status = statusFailed
+	}
+}
+
+// addPlans records what plan found would be deleted in the account
+func (a *AccountReport) addPlans(plans []RegionPlan) {
+	for _, plan := range plans {
+		a.Regions = append(a.Regions, newPlanReport(plan))
+		if plan.Err != nil {
+			a.Status = statusFailed
+		}
+	}
+}
+
+// addInventory records the default VPCs found by list or verify
+func (a *AccountReport) addInventory(inventories []RegionInventory, verify bool) {
+	for _, inventory := range inventories {
+		region := newInventoryReport(inventory, verify)
+		a.Regions = append(a.Regions, region)
+		if region.Status == statusFailed {
+			a.Status = statusFailed
+		}
+	}
+}
+
+// finish stamps the end of the run and its overall outcome
+func (r *Report) finish(err error) {
+	r.FinishedAt = time.Now().UTC()
+	r.Status = statusOf(err)
+	r.Error = errorString(err)
+	if r.Accounts == nil {
+		r.Accounts = []AccountReport{}
+	}
+}
+
+// Write the report as JSON to path, or to stdout when path is "" or "-"
+func writeReport(path string, report *Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if path == "" || path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
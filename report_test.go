@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func Test_newRegionReport(t *testing.T) {
+	tests := []struct {
+		name         string
+		region       RegionResult
+		wantStatus   string
+		wantVPCs     []string
+		wantFailures int
+	}{
+		{
+			name:       "no default VPCs",
+			region:     RegionResult{Region: "us-east-1"},
+			wantStatus: statusNoDefaultVPCs,
+		},
+		{
+			name: "deleted and skipped",
+			region: RegionResult{
+				Region:       "us-east-1",
+				SnapshotPath: "snapshots/us-east-1.json",
+				VPCs: []VPCResult{
+					{VpcID: "vpc-1", Resources: []ResourceResult{
+						newResourceResult(resourceSubnet, "subnet-1", actionDelete, nil),
+						newResourceResult(resourceVPC, "vpc-1", actionDelete, nil),
+					}},
+					{VpcID: "vpc-2", SkipReason: "in use by instance i-12345"},
+				},
+			},
+			wantStatus: statusOK,
+			wantVPCs:   []string{statusDeleted, statusSkipped},
+		},
+		{
+			name: "failed delete",
+			region: RegionResult{
+				Region: "us-west-2",
+				VPCs: []VPCResult{
+					{VpcID: "vpc-3", Err: fmt.Errorf("DependencyViolation"), Resources: []ResourceResult{
+						newResourceResult(resourceVPC, "vpc-3", actionDelete, fmt.Errorf("DependencyViolation")),
+					}},
+				},
+			},
+			wantStatus:   statusFailed,
+			wantVPCs:     []string{statusFailed},
+			wantFailures: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newRegionReport(tt.region)
+			if got.Status != tt.wantStatus {
+				t.Errorf("newRegionReport() status = %v, want %v", got.Status, tt.wantStatus)
+			}
+			var vpcStatuses []string
+			failures := 0
+			for _, vpc := range got.VPCs {
+				vpcStatuses = append(vpcStatuses, vpc.Status)
+				for _, r := range vpc.Resources {
+					if r.Result == statusFailed && r.Error != "" {
+						failures++
+					}
+				}
+			}
+			if !reflect.DeepEqual(vpcStatuses, tt.wantVPCs) {
+				t.Errorf("newRegionReport() VPC statuses = %v, want %v", vpcStatuses, tt.wantVPCs)
+			}
+			if failures != tt.wantFailures {
+				t.Errorf("newRegionReport() recorded %d failed calls, want %d", failures, tt.wantFailures)
+			}
+		})
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	account := newAccountReport(Account{ID: "222222222222"}, []SkippedRegion{{Region: "ap-east-1", Reason: "opt-in status is not-opted-in"}}, nil)
+	account.addResult(&RunResult{Regions: []RegionResult{{
+		Region: "us-west-2",
+		VPCs:   []VPCResult{{VpcID: "vpc-3", Err: fmt.Errorf("DependencyViolation")}},
+	}}})
+	report := &Report{Accounts: []AccountReport{account}}
+	report.finish(fmt.Errorf("region us-west-2: DependencyViolation"))
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	if got["status"] != statusFailed {
+		t.Errorf("report status = %v, want %v", got["status"], statusFailed)
+	}
+	accounts := got["accounts"].([]any)
+	first := accounts[0].(map[string]any)
+	if first["accountId"] != "222222222222" || first["status"] != statusFailed {
+		t.Errorf("account = %v", first)
+	}
+	skipped := first["skippedRegions"].([]any)[0].(map[string]any)
+	if skipped["region"] != "ap-east-1" || skipped["reason"] == "" {
+		t.Errorf("skipped region = %v", skipped)
+	}
+}
+
+func Test_newInventoryReport(t *testing.T) {
+	tests := []struct {
+		name       string
+		inventory  RegionInventory
+		verify     bool
+		wantStatus string
+	}{
+		{
+			name:       "list with default VPC",
+			inventory:  RegionInventory{Region: "eu-west-1", VPCs: []string{"vpc-1"}},
+			wantStatus: statusOK,
+		},
+		{
+			name:       "verify with default VPC left",
+			inventory:  RegionInventory{Region: "eu-west-1", VPCs: []string{"vpc-1"}},
+			verify:     true,
+			wantStatus: statusFailed,
+		},
+		{
+			name:       "verify with only protected VPCs",
+			inventory:  RegionInventory{Region: "eu-west-1", Protected: []ProtectedVPC{{VpcID: "vpc-2", Reason: "protected by VPC ID"}}},
+			verify:     true,
+			wantStatus: statusOK,
+		},
+		{
+			name:       "nothing found",
+			inventory:  RegionInventory{Region: "eu-west-1"},
+			verify:     true,
+			wantStatus: statusNoDefaultVPCs,
+		},
+		{
+			name:       "describe failed",
+			inventory:  RegionInventory{Region: "eu-west-1", Err: fmt.Errorf("access denied")},
+			wantStatus: statusFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newInventoryReport(tt.inventory, tt.verify)
+			if got.Status != tt.wantStatus {
+				t.Errorf("newInventoryReport() status = %v, want %v", got.Status, tt.wantStatus)
+			}
+			if len(got.VPCs) != len(tt.inventory.VPCs)+len(tt.inventory.Protected) {
+				t.Errorf("newInventoryReport() VPCs = %v", got.VPCs)
+			}
+		})
+	}
+}
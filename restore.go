@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+// The layout AWS gives every default VPC
+const (
+	defaultVPCCidr    = "172.31.0.0/16"
+	defaultSubnetBits = 20
+)
+
+// RestoreResult is the outcome of recreating the default VPC in one region.
+// Problems lists every way the VPC differs from the default layout.
+type RestoreResult struct {
+	Region         string
+	VpcID          string
+	CreatedVPC     bool
+	CreatedSubnets []string
+	Problems       []string
+	Err            error
+}
+
+// OK reports whether the region has a default VPC with the default layout
+func (r RestoreResult) OK() bool {
+	return r.Err == nil && len(r.Problems) == 0
+}
+
+// Find the region's default VPC, or nil if there isn't one
+func describeDefaultVPC(ctx context.Context, client EC2API) (*types.Vpc, error) {
+	resp, err := client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("is-default"),
+				Values: []string{"true"},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe default VPC: %w", err)
+	}
+	if len(resp.Vpcs) == 0 {
+		return nil, nil
+	}
+	return &resp.Vpcs[0], nil
+}
+
+// List the region's availability zones that get a default subnet. Local
+// and Wavelength zones are left out.
+func describeAvailabilityZones(ctx context.Context, client EC2API) ([]string, error) {
+	resp, err := client.DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("zone-type"),
+				Values: []string{"availability-zone"},
+			},
+			{
+				Name:   aws.String("state"),
+				Values: []string{"available"},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe availability zones: %w", err)
+	}
+
+	zones := []string{}
+	for _, zone := range resp.AvailabilityZones {
+		zones = append(zones, aws.ToString(zone.ZoneName))
+	}
+	sort.Strings(zones)
+	return zones, nil
+}
+
+// Create the default subnet in a zone. A subnet that already exists, such
+// as one CreateDefaultVpc made moments ago, is not an error.
+func createDefaultSubnet(ctx context.Context, client EC2API, zone string) (string, error) {
+	resp, err := client.CreateDefaultSubnet(ctx, &ec2.CreateDefaultSubnetInput{
+		AvailabilityZone: aws.String(zone),
+	})
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "DefaultSubnetAlreadyExistsInAvailabilityZone" {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create default subnet in %s: %w", zone, err)
+	}
+	return aws.ToString(resp.Subnet.SubnetId), nil
+}
+
+// Compare a default VPC against the layout AWS creates: a 172.31.0.0/16
+// VPC, a public /20 default subnet in every zone, an attached internet
+// gateway and a main route table that sends 0.0.0.0/0 to it
+func verifyDefaultLayout(vpc types.Vpc, zones []string, subnets []types.Subnet, igws []types.InternetGateway, routeTables []types.RouteTable) []string {
+	var problems []string
+	vpcID := aws.ToString(vpc.VpcId)
+
+	if !aws.ToBool(vpc.IsDefault) {
+		problems = append(problems, fmt.Sprintf("VPC %s is not the default VPC", vpcID))
+	}
+	if cidr := aws.ToString(vpc.CidrBlock); cidr != defaultVPCCidr {
+		problems = append(problems, fmt.Sprintf("VPC %s has CIDR %s, want %s", vpcID, cidr, defaultVPCCidr))
+	}
+
+	vpcPrefix := netip.MustParsePrefix(defaultVPCCidr)
+	for _, zone := range zones {
+		i := slices.IndexFunc(subnets, func(s types.Subnet) bool {
+			return aws.ToBool(s.DefaultForAz) && aws.ToString(s.AvailabilityZone) == zone
+		})
+		if i < 0 {
+			problems = append(problems, fmt.Sprintf("no default subnet in %s", zone))
+			continue
+		}
+		subnet := subnets[i]
+		subnetID := aws.ToString(subnet.SubnetId)
+		prefix, err := netip.ParsePrefix(aws.ToString(subnet.CidrBlock))
+		if err != nil || prefix.Bits() != defaultSubnetBits || !vpcPrefix.Overlaps(prefix) {
+			problems = append(problems, fmt.Sprintf("subnet %s has CIDR %s, want a /%d in %s", subnetID, aws.ToString(subnet.CidrBlock), defaultSubnetBits, defaultVPCCidr))
+		}
+		if !aws.ToBool(subnet.MapPublicIpOnLaunch) {
+			problems = append(problems, fmt.Sprintf("subnet %s does not assign public IPs", subnetID))
+		}
+	}
+
+	if len(igws) == 0 {
+		problems = append(problems, fmt.Sprintf("no internet gateway attached to VPC %s", vpcID))
+		return problems
+	}
+	igwID := aws.ToString(igws[0].InternetGatewayId)
+
+	i := slices.IndexFunc(routeTables, isMainRouteTable)
+	if i < 0 {
+		problems = append(problems, fmt.Sprintf("no main route table in VPC %s", vpcID))
+		return problems
+	}
+	hasDefaultRoute := slices.ContainsFunc(routeTables[i].Routes, func(r types.Route) bool {
+		return aws.ToString(r.DestinationCidrBlock) == "0.0.0.0/0" && aws.ToString(r.GatewayId) == igwID
+	})
+	if !hasDefaultRoute {
+		problems = append(problems, fmt.Sprintf("main route table %s has no 0.0.0.0/0 route to %s", aws.ToString(routeTables[i].RouteTableId), igwID))
+	}
+	return problems
+}
+
+// Recreate the default VPC in a region, or fill in the default subnets an
+// existing one is missing, then check the result against the default layout
+func restoreRegion(ctx context.Context, client EC2API, region string) RestoreResult {
+	ctx = withLogAttrs(ctx, logKeyRegion, region)
+	result := RestoreResult{Region: region}
+
+	vpc, err := describeDefaultVPC(ctx, client)
+	if err != nil {
+		result.Err = fmt.Errorf("region %s: %w", region, err)
+		return result
+	}
+	if vpc == nil {
+		resp, err := client.CreateDefaultVpc(ctx, &ec2.CreateDefaultVpcInput{})
+		if err != nil {
+			result.Err = fmt.Errorf("failed to create default VPC in region %s: %w", region, err)
+			return result
+		}
+		vpc = resp.Vpc
+		result.CreatedVPC = true
+		loggerFrom(ctx).Info("created", logKeyResourceType, resourceVPC, logKeyResourceID, aws.ToString(vpc.VpcId))
+	}
+	result.VpcID = aws.ToString(vpc.VpcId)
+
+	zones, err := describeAvailabilityZones(ctx, client)
+	if err != nil {
+		result.Err = fmt.Errorf("region %s: %w", region, err)
+		return result
+	}
+	subnets, err := describeSubnets(ctx, client, result.VpcID)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to describe subnets in region %s: %w", region, err)
+		return result
+	}
+	for _, zone := range zones {
+		if slices.ContainsFunc(subnets, func(s types.Subnet) bool {
+			return aws.ToBool(s.DefaultForAz) && aws.ToString(s.AvailabilityZone) == zone
+		}) {
+			continue
+		}
+		subnetID, err := createDefaultSubnet(ctx, client, zone)
+		if err != nil {
+			result.Err = fmt.Errorf("region %s: %w", region, err)
+			return result
+		}
+		if subnetID != "" {
+			result.CreatedSubnets = append(result.CreatedSubnets, fmt.Sprintf("%s (%s)", subnetID, zone))
+			loggerFrom(ctx).Info("created", logKeyResourceType, resourceSubnet, logKeyResourceID, subnetID, "availability_zone", zone)
+		}
+	}
+
+	// Describe everything again so the check sees what EC2 ended up with
+	restored, err := describeVPC(ctx, client, result.VpcID)
+	if err != nil {
+		result.Err = fmt.Errorf("region %s: %w", region, err)
+		return result
+	}
+	if subnets, err = describeSubnets(ctx, client, result.VpcID); err != nil {
+		result.Err = fmt.Errorf("failed to describe subnets in region %s: %w", region, err)
+		return result
+	}
+	igws, err := describeInternetGateways(ctx, client, result.VpcID)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to describe internet gateways in region %s: %w", region, err)
+		return result
+	}
+	routeTables, err := describeRouteTables(ctx, client, result.VpcID)
+	if err != nil {
+		result.Err = fmt.Errorf("region %s: %w", region, err)
+		return result
+	}
+	result.Problems = verifyDefaultLayout(restored, zones, subnets, igws, routeTables)
+	return result
+}
+
+// RestoreAllDefaultVPCs recreates the default VPC in every region, at most
+// concurrency regions at once. The returned error joins every region that
+// failed or doesn't match the default layout.
+func RestoreAllDefaultVPCs(ctx context.Context, regions []string, newClient ClientFactory, concurrency int) ([]RestoreResult, error) {
+	results := make([]RestoreResult, len(regions))
+
+	runBounded(len(regions), concurrency, func(i int) {
+		results[i] = restoreRegion(ctx, newClient(regions[i]), regions[i])
+	})
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Region < results[j].Region })
+
+	var errs []error
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			errs = append(errs, result.Err)
+		case len(result.Problems) > 0:
+			errs = append(errs, fmt.Errorf("region %s: default VPC %s does not match the default layout", result.Region, result.VpcID))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// Write what was recreated in each region and anything that looks wrong
+func printRestore(w io.Writer, results []RestoreResult) {
+	for _, result := range results {
+		fmt.Fprintf(w, "Region %s\n", result.Region)
+		if result.Err != nil {
+			fmt.Fprintf(w, "  Error: %v\n", result.Err)
+			continue
+		}
+		if result.CreatedVPC {
+			fmt.Fprintf(w, "  created VPC: %s\n", result.VpcID)
+		} else {
+			fmt.Fprintf(w, "  default VPC already exists: %s\n", result.VpcID)
+		}
+		for _, subnet := range result.CreatedSubnets {
+			fmt.Fprintf(w, "  created subnet: %s\n", subnet)
+		}
+		for _, problem := range result.Problems {
+			fmt.Fprintf(w, "  problem: %s\n", problem)
+		}
+		if result.OK() {
+			fmt.Fprintln(w, "  matches the default layout")
+		}
+	}
+}
+
+// Read the regions out of snapshot files written by an earlier run. Every
+// snapshot must be for accountID, the account being restored.
+func snapshotRegions(paths []string, accountID string) ([]string, error) {
+	var regions []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var snapshot RegionSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, fmt.Errorf("failed to read snapshot %s: %w", path, err)
+		}
+		if snapshot.Version != snapshotVersion {
+			return nil, fmt.Errorf("snapshot %s has version %d, want %d", path, snapshot.Version, snapshotVersion)
+		}
+		if snapshot.AccountID != accountID {
+			return nil, fmt.Errorf("snapshot %s is for account %s but the credentials are for %s", path, snapshot.AccountID, accountID)
+		}
+		if !slices.Contains(regions, snapshot.Region) {
+			regions = append(regions, snapshot.Region)
+		}
+	}
+	return regions, nil
+}
+
+// restoreMain runs the restore subcommand with its own flags. It only
+// restores the regions picked by --regions or by snapshot files from an
+// earlier run, narrowed further by --exclude-regions, and asks for the
+// account ID to be typed first unless --yes is passed.
+func restoreMain(args []string) {
+	flags := flag.NewFlagSet(cmdRestore, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: remove-all-default-vpc restore [flags] [snapshot.json ...]")
+		flags.PrintDefaults()
+	}
+	includeRegions := flags.String("regions", "", "comma separated regions to restore, globs such as eu-* are allowed; required unless snapshot files are given")
+	excludeRegions := flags.String("exclude-regions", "", "comma separated regions to leave alone, globs such as eu-* are allowed")
+	concurrency := flags.Int("concurrency", 8, "maximum number of regions restored at once")
+	rateLimit := flags.Float64("rate-limit", 20, "average EC2 API requests per second across all regions, 0 for no limit")
+	rateBurst := flags.Int("rate-burst", 40, "EC2 API requests allowed in a burst above --rate-limit")
+	logFormat := flags.String("log-format", logText, "log format written to stderr: text or json")
+	logLevel := flags.String("log-level", "info", "lowest log level to write: debug, info, warn or error")
+	yes := flags.Bool("yes", false, "restore without asking for the account ID to be typed; required when not run from a terminal")
+	flags.Parse(args)
+
+	if *includeRegions == "" && flags.NArg() == 0 {
+		fmt.Println("Invalid options: pass --regions or snapshot files to choose the regions to restore")
+		os.Exit(2)
+	}
+	if !*yes && !isTerminal(os.Stdin) {
+		fmt.Println("Invalid options: not running in a terminal, pass --yes to restore without typing the account ID")
+		os.Exit(2)
+	}
+
+	logger, err := newLogger(os.Stderr, *logFormat, *logLevel)
+	if err != nil {
+		fmt.Printf("Invalid options: %v\n", err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
+	limiter := NewRateLimiter(*rateLimit, *rateBurst)
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		fmt.Printf("Unable to load AWS SDK config: %v", err)
+		os.Exit(1)
+	}
+
+	accountID, err := callerAccountID(ctx, cfg)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	var fromSnapshots []string
+	if flags.NArg() > 0 {
+		if fromSnapshots, err = snapshotRegions(flags.Args(), accountID); err != nil {
+			fmt.Printf("Invalid options: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	newClient := withRetry(NewClientFactory(cfg, limiter.apiOption()), DefaultRetryPolicy())
+	regions, err := selectRegions(ctx, newClient(cfg.Region), RegionSelection{
+		Include: splitList(*includeRegions),
+		Exclude: splitList(*excludeRegions),
+	})
+	if err == nil && flags.NArg() > 0 {
+		regions.Regions = slices.DeleteFunc(regions.Regions, func(region string) bool {
+			return !slices.Contains(fromSnapshots, region)
+		})
+	}
+	if err == nil && !*yes && len(regions.Regions) > 0 {
+		account := Account{ID: accountID, Name: accountAlias(ctx, cfg)}
+		err = confirmApply(os.Stdout, accountID, confirmRestore, func(w io.Writer) {
+			fmt.Fprintf(w, "Account %s\n", account)
+			for _, region := range regions.Regions {
+				fmt.Fprintf(w, "Region %s: restore the default VPC\n", region)
+			}
+		})
+	}
+	if err == nil {
+		var results []RestoreResult
+		results, err = RestoreAllDefaultVPCs(ctx, regions.Regions, newClient, *concurrency)
+		printRestore(os.Stdout, results)
+		requests, perSecond := limiter.Requests()
+		slog.Info("made EC2 API requests", "requests", requests, "per_second", perSecond)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
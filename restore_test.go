@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+func defaultSubnet(id, zone, cidr string) types.Subnet {
+	return types.Subnet{
+		SubnetId:            aws.String(id),
+		AvailabilityZone:    aws.String(zone),
+		CidrBlock:           aws.String(cidr),
+		DefaultForAz:        aws.Bool(true),
+		MapPublicIpOnLaunch: aws.Bool(true),
+	}
+}
+
+func Test_verifyDefaultLayout(t *testing.T) {
+	vpc := types.Vpc{VpcId: aws.String("vpc-12345"), CidrBlock: aws.String(defaultVPCCidr), IsDefault: aws.Bool(true)}
+	zones := []string{"us-east-1a", "us-east-1b"}
+	subnets := []types.Subnet{
+		defaultSubnet("subnet-a", "us-east-1a", "172.31.0.0/20"),
+		defaultSubnet("subnet-b", "us-east-1b", "172.31.16.0/20"),
+	}
+	igws := []types.InternetGateway{{InternetGatewayId: aws.String("igw-12345")}}
+	routeTables := []types.RouteTable{{
+		RouteTableId: aws.String("rtb-12345"),
+		Associations: []types.RouteTableAssociation{{Main: aws.Bool(true)}},
+		Routes:       []types.Route{{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-12345")}},
+	}}
+
+	tests := []struct {
+		name        string
+		vpc         types.Vpc
+		subnets     []types.Subnet
+		igws        []types.InternetGateway
+		routeTables []types.RouteTable
+		want        []string
+	}{
+		{
+			name:        "default layout",
+			vpc:         vpc,
+			subnets:     subnets,
+			igws:        igws,
+			routeTables: routeTables,
+		},
+		{
+			name:        "missing subnet and wrong CIDR",
+			vpc:         types.Vpc{VpcId: aws.String("vpc-12345"), CidrBlock: aws.String("10.0.0.0/16"), IsDefault: aws.Bool(true)},
+			subnets:     subnets[:1],
+			igws:        igws,
+			routeTables: routeTables,
+			want: []string{
+				"VPC vpc-12345 has CIDR 10.0.0.0/16, want 172.31.0.0/16",
+				"no default subnet in us-east-1b",
+			},
+		},
+		{
+			name:        "no internet gateway",
+			vpc:         vpc,
+			subnets:     subnets,
+			routeTables: routeTables,
+			want:        []string{"no internet gateway attached to VPC vpc-12345"},
+		},
+		{
+			name:    "main route table without a default route",
+			vpc:     vpc,
+			subnets: subnets,
+			igws:    igws,
+			routeTables: []types.RouteTable{{
+				RouteTableId: aws.String("rtb-12345"),
+				Associations: []types.RouteTableAssociation{{Main: aws.Bool(true)}},
+			}},
+			want: []string{"main route table rtb-12345 has no 0.0.0.0/0 route to igw-12345"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyDefaultLayout(tt.vpc, zones, tt.subnets, tt.igws, tt.routeTables); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("verifyDefaultLayout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_restoreRegion(t *testing.T) {
+	var created bool
+	var subnets []types.Subnet
+	client := &MockEC2Client{
+		describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+			if !created {
+				return &ec2.DescribeVpcsOutput{}, nil
+			}
+			return &ec2.DescribeVpcsOutput{
+				Vpcs: []types.Vpc{{VpcId: aws.String("vpc-new"), CidrBlock: aws.String(defaultVPCCidr), IsDefault: aws.Bool(true)}},
+			}, nil
+		},
+		createDefaultVpcFunc: func(ctx context.Context, input *ec2.CreateDefaultVpcInput, optFns ...func(*ec2.Options)) (*ec2.CreateDefaultVpcOutput, error) {
+			created = true
+			// CreateDefaultVpc only managed one of the zones
+			subnets = append(subnets, defaultSubnet("subnet-a", "us-east-1a", "172.31.0.0/20"))
+			return &ec2.CreateDefaultVpcOutput{Vpc: &types.Vpc{VpcId: aws.String("vpc-new")}}, nil
+		},
+		describeAvailabilityZonesFunc: func(ctx context.Context, input *ec2.DescribeAvailabilityZonesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeAvailabilityZonesOutput, error) {
+			return &ec2.DescribeAvailabilityZonesOutput{
+				AvailabilityZones: []types.AvailabilityZone{{ZoneName: aws.String("us-east-1c")}, {ZoneName: aws.String("us-east-1a")}, {ZoneName: aws.String("us-east-1b")}},
+			}, nil
+		},
+		describeSubnetsFunc: func(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+			return &ec2.DescribeSubnetsOutput{Subnets: subnets}, nil
+		},
+		createDefaultSubnetFunc: func(ctx context.Context, input *ec2.CreateDefaultSubnetInput, optFns ...func(*ec2.Options)) (*ec2.CreateDefaultSubnetOutput, error) {
+			if *input.AvailabilityZone == "us-east-1c" {
+				// Created by EC2 in the meantime, and not visible yet
+				return nil, &smithy.GenericAPIError{Code: "DefaultSubnetAlreadyExistsInAvailabilityZone"}
+			}
+			subnet := defaultSubnet("subnet-b", "us-east-1b", "172.31.16.0/20")
+			subnets = append(subnets, subnet)
+			return &ec2.CreateDefaultSubnetOutput{Subnet: &subnet}, nil
+		},
+		describeInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
+			return &ec2.DescribeInternetGatewaysOutput{InternetGateways: []types.InternetGateway{{InternetGatewayId: aws.String("igw-new")}}}, nil
+		},
+		describeRouteTablesFunc: func(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+			return &ec2.DescribeRouteTablesOutput{
+				RouteTables: []types.RouteTable{{
+					RouteTableId: aws.String("rtb-new"),
+					Associations: []types.RouteTableAssociation{{Main: aws.Bool(true)}},
+					Routes:       []types.Route{{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-new")}},
+				}},
+			}, nil
+		},
+	}
+
+	got := restoreRegion(context.Background(), client, "us-east-1")
+	if got.Err != nil {
+		t.Fatalf("restoreRegion() error = %v", got.Err)
+	}
+	if !got.CreatedVPC || got.VpcID != "vpc-new" {
+		t.Errorf("restoreRegion() VPC = %s, created = %v", got.VpcID, got.CreatedVPC)
+	}
+	if want := []string{"subnet-b (us-east-1b)"}; !reflect.DeepEqual(got.CreatedSubnets, want) {
+		t.Errorf("restoreRegion() created subnets %v, want %v", got.CreatedSubnets, want)
+	}
+	// us-east-1c never showed up in DescribeSubnets
+	if want := []string{"no default subnet in us-east-1c"}; !reflect.DeepEqual(got.Problems, want) {
+		t.Errorf("restoreRegion() problems = %v, want %v", got.Problems, want)
+	}
+}
+
+func Test_snapshotRegions(t *testing.T) {
+	dir := t.TempDir()
+	takenAt := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	var paths []string
+	for _, region := range []string{"us-east-1", "eu-west-1", "us-east-1"} {
+		path, err := newSnapshotWriter(dir, "222222222222", takenAt)(RegionSnapshot{Region: region})
+		if err != nil {
+			t.Fatalf("SnapshotWriter() error = %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	got, err := snapshotRegions(paths, "222222222222")
+	if err != nil {
+		t.Fatalf("snapshotRegions() error = %v", err)
+	}
+	if want := []string{"us-east-1", "eu-west-1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshotRegions() = %v, want %v", got, want)
+	}
+
+	if _, err := snapshotRegions(paths, "111111111111"); err == nil {
+		t.Error("snapshotRegions() for another account error = nil, want an error")
+	}
+}
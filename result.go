@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Resource types recorded in results
+const (
+	resourceInternetGateway           = "internet-gateway"
+	resourceSubnet                    = "subnet"
+	resourceRouteTable                = "route-table"
+	resourceNetworkACL                = "network-acl"
+	resourceSecurityGroup             = "security-group"
+	resourceNatGateway                = "nat-gateway"
+	resourceVpcEndpoint               = "vpc-endpoint"
+	resourceElasticIP                 = "elastic-ip"
+	resourceNetworkInterface          = "network-interface"
+	resourceEgressOnlyInternetGateway = "egress-only-internet-gateway"
+	resourceSubnetCidrBlock           = "subnet-cidr-block"
+	resourceVpcCidrBlock              = "vpc-cidr-block"
+	resourceVPC                       = "vpc"
+)
+
+// Actions recorded in results
+const (
+	actionDetach       = "detach"
+	actionDelete       = "delete"
+	actionRevoke       = "revoke"
+	actionRelease      = "release"
+	actionWait         = "wait"
+	actionDisassociate = "disassociate"
+)
+
+// ResourceResult is the outcome of a single call against a resource. Rules
+// lists the security group rules a revoke removed.
+type ResourceResult struct {
+	Type   string
+	ID     string
+	Action string
+	Rules  []string
+	Err    error
+}
+
+func newResourceResult(resourceType, id, action string, err error) ResourceResult {
+	return ResourceResult{Type: resourceType, ID: id, Action: action, Err: err}
+}
+
+// VPCResult holds everything done to a single default VPC. SkipReason is
+// set when the VPC was deliberately left alone, and Protected when that was
+// because of --protect-vpc or --protect-tag.
+type VPCResult struct {
+	VpcID      string
+	Resources  []ResourceResult
+	SkipReason string
+	Protected  bool
+	Err        error
+}
+
+// Deleted reports whether the VPC itself was removed
+func (v VPCResult) Deleted() bool {
+	for _, r := range v.Resources {
+		if r.Type == resourceVPC && r.Action == actionDelete && r.Err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// RegionResult holds the outcome for every default VPC in a region. Err is
+// set when the region could not be processed at all. SnapshotPath is where
+// the region was saved before anything was deleted.
+type RegionResult struct {
+	Region       string
+	VPCs         []VPCResult
+	SnapshotPath string
+	Err          error
+}
+
+// Failed reports whether anything in the region went wrong
+func (r RegionResult) Failed() bool {
+	if r.Err != nil {
+		return true
+	}
+	for _, vpc := range r.VPCs {
+		if vpc.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// RunResult is the outcome of DeleteAllDefaultVPCs across all regions
+type RunResult struct {
+	Regions []RegionResult
+	Skipped []SkippedRegion
+}
+
+// Err joins every region and VPC failure, or returns nil if there were none
+func (r *RunResult) Err() error {
+	var errs []error
+	for _, region := range r.Regions {
+		if region.Err != nil {
+			errs = append(errs, region.Err)
+		}
+		for _, vpc := range region.VPCs {
+			if vpc.Err != nil {
+				errs = append(errs, vpc.Err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Write a one line status per region followed by any failures
+func printSummary(w io.Writer, result *RunResult) {
+	fmt.Fprintln(w, "Summary")
+	for _, region := range result.Regions {
+		switch {
+		case region.Err != nil:
+			fmt.Fprintf(w, "  %s: failed: %v\n", region.Region, region.Err)
+		case len(region.VPCs) == 0:
+			fmt.Fprintf(w, "  %s: no default VPCs\n", region.Region)
+		}
+		if region.SnapshotPath != "" {
+			fmt.Fprintf(w, "  %s: snapshot saved to %s\n", region.Region, region.SnapshotPath)
+		}
+		for _, vpc := range region.VPCs {
+			if vpc.Protected {
+				fmt.Fprintf(w, "  %s: protected %s: %s\n", region.Region, vpc.VpcID, vpc.SkipReason)
+				continue
+			}
+			if vpc.SkipReason != "" {
+				fmt.Fprintf(w, "  %s: skipped %s: %s\n", region.Region, vpc.VpcID, vpc.SkipReason)
+				continue
+			}
+			if vpc.Deleted() {
+				fmt.Fprintf(w, "  %s: deleted %s (%d calls)\n", region.Region, vpc.VpcID, len(vpc.Resources))
+			} else {
+				fmt.Fprintf(w, "  %s: failed %s: %v\n", region.Region, vpc.VpcID, vpc.Err)
+			}
+			for _, r := range vpc.Resources {
+				for _, rule := range r.Rules {
+					fmt.Fprintf(w, "    revoked from %s: %s\n", r.ID, rule)
+				}
+			}
+		}
+	}
+	for _, skipped := range result.Skipped {
+		fmt.Fprintf(w, "  %s: skipped: %s\n", skipped.Region, skipped.Reason)
+	}
+}
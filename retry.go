@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/smithy-go"
+)
+
+// defaultRetryCodes are the error codes that usually clear up on their own:
+// eventual consistency after a detach or ENI cleanup, and throttling
+var defaultRetryCodes = []string{
+	"DependencyViolation",
+	"RequestLimitExceeded",
+	"Throttling",
+	"ThrottlingException",
+	"InternalError",
+	"Unavailable",
+}
+
+// RetryPolicy controls how failed mutating calls are retried
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Codes       []string
+
+	// sleep waits between attempts, and is replaced in tests
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// DefaultRetryPolicy returns the policy used when no flags override it
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+		Codes:       defaultRetryCodes,
+	}
+}
+
+func (p RetryPolicy) validate() error {
+	if p.MaxAttempts < 1 {
+		return errors.New("--retry-max-attempts must be at least 1")
+	}
+	if p.BaseDelay <= 0 || p.MaxDelay < p.BaseDelay {
+		return errors.New("--retry-base-delay must be positive and no more than --retry-max-delay")
+	}
+	return nil
+}
+
+// retryable reports whether err carries one of the policy's error codes
+func (p RetryPolicy) retryable(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && slices.Contains(p.Codes, apiErr.ErrorCode())
+}
+
+// delay picks how long to wait before the given retry, starting at 1. It is
+// exponential with full jitter, capped at MaxDelay.
+func (p RetryPolicy) delay(retry int) time.Duration {
+	ceiling := p.MaxDelay
+	if shift := retry - 1; shift < 32 {
+		if d := p.BaseDelay << shift; d > 0 && d < ceiling {
+			ceiling = d
+		}
+	}
+	return rand.N(ceiling) + 1
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// waitUntil calls done every interval until it reports true, returns an
+// error or timeout passes
+func waitUntil(ctx context.Context, timeout, interval time.Duration, done func() (bool, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		ok, err := done()
+		if ok || err != nil {
+			return err
+		}
+		if err := sleepContext(ctx, interval); err != nil {
+			return fmt.Errorf("gave up waiting after %s: %w", timeout, err)
+		}
+	}
+}
+
+// retry calls fn until it succeeds, fails with an error the policy doesn't
+// retry, or runs out of attempts
+func retry[T any](ctx context.Context, policy RetryPolicy, operation string, fn func() (T, error)) (T, error) {
+	sleep := policy.sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+
+	for attempt := 1; ; attempt++ {
+		out, err := fn()
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			if err != nil && attempt > 1 {
+				err = fmt.Errorf("%s failed after %d attempts: %w", operation, attempt, err)
+			}
+			return out, err
+		}
+
+		d := policy.delay(attempt)
+		loggerFrom(ctx).Warn("retrying", "operation", operation, "attempt", attempt, "delay", d, logKeyError, err)
+		if sleepErr := sleep(ctx, d); sleepErr != nil {
+			return out, err
+		}
+	}
+}
+
+// RetryEC2Client wraps an EC2API and retries the calls that change
+// resources according to Policy. Describe calls are passed straight through
+// and rely on the SDK's own retries.
+type RetryEC2Client struct {
+	EC2API
+	Policy RetryPolicy
+}
+
+// withRetry wraps every client newClient makes in a RetryEC2Client
+func withRetry(newClient ClientFactory, policy RetryPolicy) ClientFactory {
+	return func(region string) EC2API {
+		return &RetryEC2Client{EC2API: newClient(region), Policy: policy}
+	}
+}
+
+func (c *RetryEC2Client) DeleteVpc(ctx context.Context, input *ec2.DeleteVpcInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVpcOutput, error) {
+	return retry(ctx, c.Policy, "DeleteVpc", func() (*ec2.DeleteVpcOutput, error) {
+		return c.EC2API.DeleteVpc(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) DeleteSecurityGroup(ctx context.Context, input *ec2.DeleteSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error) {
+	return retry(ctx, c.Policy, "DeleteSecurityGroup", func() (*ec2.DeleteSecurityGroupOutput, error) {
+		return c.EC2API.DeleteSecurityGroup(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) RevokeSecurityGroupIngress(ctx context.Context, input *ec2.RevokeSecurityGroupIngressInput, optFns ...func(*ec2.Options)) (*ec2.RevokeSecurityGroupIngressOutput, error) {
+	return retry(ctx, c.Policy, "RevokeSecurityGroupIngress", func() (*ec2.RevokeSecurityGroupIngressOutput, error) {
+		return c.EC2API.RevokeSecurityGroupIngress(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) RevokeSecurityGroupEgress(ctx context.Context, input *ec2.RevokeSecurityGroupEgressInput, optFns ...func(*ec2.Options)) (*ec2.RevokeSecurityGroupEgressOutput, error) {
+	return retry(ctx, c.Policy, "RevokeSecurityGroupEgress", func() (*ec2.RevokeSecurityGroupEgressOutput, error) {
+		return c.EC2API.RevokeSecurityGroupEgress(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) DeleteSubnet(ctx context.Context, input *ec2.DeleteSubnetInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSubnetOutput, error) {
+	return retry(ctx, c.Policy, "DeleteSubnet", func() (*ec2.DeleteSubnetOutput, error) {
+		return c.EC2API.DeleteSubnet(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) DeleteRouteTable(ctx context.Context, input *ec2.DeleteRouteTableInput, optFns ...func(*ec2.Options)) (*ec2.DeleteRouteTableOutput, error) {
+	return retry(ctx, c.Policy, "DeleteRouteTable", func() (*ec2.DeleteRouteTableOutput, error) {
+		return c.EC2API.DeleteRouteTable(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) DetachInternetGateway(ctx context.Context, input *ec2.DetachInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DetachInternetGatewayOutput, error) {
+	return retry(ctx, c.Policy, "DetachInternetGateway", func() (*ec2.DetachInternetGatewayOutput, error) {
+		return c.EC2API.DetachInternetGateway(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) DeleteInternetGateway(ctx context.Context, input *ec2.DeleteInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DeleteInternetGatewayOutput, error) {
+	return retry(ctx, c.Policy, "DeleteInternetGateway", func() (*ec2.DeleteInternetGatewayOutput, error) {
+		return c.EC2API.DeleteInternetGateway(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) DeleteNetworkAcl(ctx context.Context, input *ec2.DeleteNetworkAclInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkAclOutput, error) {
+	return retry(ctx, c.Policy, "DeleteNetworkAcl", func() (*ec2.DeleteNetworkAclOutput, error) {
+		return c.EC2API.DeleteNetworkAcl(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) CreateDefaultSubnet(ctx context.Context, input *ec2.CreateDefaultSubnetInput, optFns ...func(*ec2.Options)) (*ec2.CreateDefaultSubnetOutput, error) {
+	return retry(ctx, c.Policy, "CreateDefaultSubnet", func() (*ec2.CreateDefaultSubnetOutput, error) {
+		return c.EC2API.CreateDefaultSubnet(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) DeleteNatGateway(ctx context.Context, input *ec2.DeleteNatGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNatGatewayOutput, error) {
+	return retry(ctx, c.Policy, "DeleteNatGateway", func() (*ec2.DeleteNatGatewayOutput, error) {
+		return c.EC2API.DeleteNatGateway(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) DeleteVpcEndpoints(ctx context.Context, input *ec2.DeleteVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVpcEndpointsOutput, error) {
+	return retry(ctx, c.Policy, "DeleteVpcEndpoints", func() (*ec2.DeleteVpcEndpointsOutput, error) {
+		return c.EC2API.DeleteVpcEndpoints(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) ReleaseAddress(ctx context.Context, input *ec2.ReleaseAddressInput, optFns ...func(*ec2.Options)) (*ec2.ReleaseAddressOutput, error) {
+	return retry(ctx, c.Policy, "ReleaseAddress", func() (*ec2.ReleaseAddressOutput, error) {
+		return c.EC2API.ReleaseAddress(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) DeleteNetworkInterface(ctx context.Context, input *ec2.DeleteNetworkInterfaceInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error) {
+	return retry(ctx, c.Policy, "DeleteNetworkInterface", func() (*ec2.DeleteNetworkInterfaceOutput, error) {
+		return c.EC2API.DeleteNetworkInterface(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) DeleteEgressOnlyInternetGateway(ctx context.Context, input *ec2.DeleteEgressOnlyInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DeleteEgressOnlyInternetGatewayOutput, error) {
+	return retry(ctx, c.Policy, "DeleteEgressOnlyInternetGateway", func() (*ec2.DeleteEgressOnlyInternetGatewayOutput, error) {
+		return c.EC2API.DeleteEgressOnlyInternetGateway(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) DisassociateSubnetCidrBlock(ctx context.Context, input *ec2.DisassociateSubnetCidrBlockInput, optFns ...func(*ec2.Options)) (*ec2.DisassociateSubnetCidrBlockOutput, error) {
+	return retry(ctx, c.Policy, "DisassociateSubnetCidrBlock", func() (*ec2.DisassociateSubnetCidrBlockOutput, error) {
+		return c.EC2API.DisassociateSubnetCidrBlock(ctx, input, optFns...)
+	})
+}
+
+func (c *RetryEC2Client) DisassociateVpcCidrBlock(ctx context.Context, input *ec2.DisassociateVpcCidrBlockInput, optFns ...func(*ec2.Options)) (*ec2.DisassociateVpcCidrBlockOutput, error) {
+	return retry(ctx, c.Policy, "DisassociateVpcCidrBlock", func() (*ec2.DisassociateVpcCidrBlockOutput, error) {
+		return c.EC2API.DisassociateVpcCidrBlock(ctx, input, optFns...)
+	})
+}
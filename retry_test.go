@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/smithy-go"
+)
+
+func Test_retry(t *testing.T) {
+	dependencyViolation := &smithy.GenericAPIError{Code: "DependencyViolation"}
+	unauthorized := &smithy.GenericAPIError{Code: "UnauthorizedOperation"}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "succeeds first time",
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "transient dependency violation",
+			errs:      []error{dependencyViolation, fmt.Errorf("wrapped: %w", dependencyViolation), nil},
+			wantCalls: 3,
+		},
+		{
+			name:      "error that isn't retried",
+			errs:      []error{unauthorized, nil},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "gives up after max attempts",
+			errs:      []error{dependencyViolation, dependencyViolation, dependencyViolation, nil},
+			wantCalls: 3,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := DefaultRetryPolicy()
+			policy.MaxAttempts = 3
+			var slept []time.Duration
+			policy.sleep = func(ctx context.Context, d time.Duration) error {
+				slept = append(slept, d)
+				return nil
+			}
+
+			calls := 0
+			_, err := retry(context.Background(), policy, "DeleteSubnet", func() (struct{}, error) {
+				calls++
+				return struct{}{}, tt.errs[calls-1]
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("retry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("retry() made %d calls, want %d", calls, tt.wantCalls)
+			}
+			if len(slept) != calls-1 {
+				t.Errorf("retry() slept %d times for %d calls", len(slept), calls)
+			}
+		})
+	}
+}
+
+func Test_waitUntil(t *testing.T) {
+	tests := []struct {
+		name      string
+		doneAfter int
+		err       error
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "done straight away",
+			doneAfter: 1,
+			wantCalls: 1,
+		},
+		{
+			name:      "done after a few checks",
+			doneAfter: 3,
+			wantCalls: 3,
+		},
+		{
+			name:      "check fails",
+			doneAfter: 3,
+			err:       &smithy.GenericAPIError{Code: "UnauthorizedOperation"},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "times out",
+			doneAfter: 1000,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := waitUntil(context.Background(), 50*time.Millisecond, time.Millisecond, func() (bool, error) {
+				calls++
+				return calls >= tt.doneAfter, tt.err
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("waitUntil() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantCalls > 0 && calls != tt.wantCalls {
+				t.Errorf("waitUntil() made %d checks, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_delay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{retry: 1, max: 100 * time.Millisecond},
+		{retry: 3, max: 400 * time.Millisecond},
+		{retry: 10, max: time.Second},
+		{retry: 100, max: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.retry), func(t *testing.T) {
+			for range 50 {
+				if d := policy.delay(tt.retry); d <= 0 || d > tt.max {
+					t.Fatalf("delay(%d) = %v, want between 0 and %v", tt.retry, d, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryEC2Client(t *testing.T) {
+	deletes := 0
+	describes := 0
+	client := &RetryEC2Client{
+		EC2API: &MockEC2Client{
+			deleteSubnetFunc: func(ctx context.Context, input *ec2.DeleteSubnetInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSubnetOutput, error) {
+				deletes++
+				if deletes == 1 {
+					return nil, &smithy.GenericAPIError{Code: "RequestLimitExceeded"}
+				}
+				return &ec2.DeleteSubnetOutput{}, nil
+			},
+			describeSubnetsFunc: func(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+				describes++
+				return nil, &smithy.GenericAPIError{Code: "RequestLimitExceeded"}
+			},
+		},
+		Policy: DefaultRetryPolicy(),
+	}
+	client.Policy.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	if _, err := client.DeleteSubnet(context.Background(), &ec2.DeleteSubnetInput{SubnetId: aws.String("subnet-12345")}); err != nil {
+		t.Errorf("DeleteSubnet() error = %v", err)
+	}
+	if deletes != 2 {
+		t.Errorf("DeleteSubnet() made %d calls, want 2", deletes)
+	}
+
+	if _, err := client.DescribeSubnets(context.Background(), &ec2.DescribeSubnetsInput{}); err == nil {
+		t.Errorf("DescribeSubnets() expected an error")
+	}
+	if describes != 1 {
+		t.Errorf("DescribeSubnets() made %d calls, want 1 since describes are not retried", describes)
+	}
+}
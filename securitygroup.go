@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// securityGroupIDs lists the IDs of groups
+func securityGroupIDs(groups []types.SecurityGroup) []string {
+	var ids []string
+	for _, sg := range groups {
+		ids = append(ids, aws.ToString(sg.GroupId))
+	}
+	return ids
+}
+
+// referencedGroups lists the groups among vpcGroups, other than sg itself,
+// that sg's rules refer to. A reference stops the group it names being
+// deleted until the rule is revoked.
+func referencedGroups(sg types.SecurityGroup, vpcGroups []string) []string {
+	var ids []string
+	for _, permission := range slices.Concat(sg.IpPermissions, sg.IpPermissionsEgress) {
+		for _, pair := range permission.UserIdGroupPairs {
+			id := aws.ToString(pair.GroupId)
+			if id != aws.ToString(sg.GroupId) && slices.Contains(vpcGroups, id) && !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// groupReferencePermissions keeps the parts of rules that refer to the
+// groups in referenced
+func groupReferencePermissions(permissions []types.IpPermission, referenced []string) []types.IpPermission {
+	var references []types.IpPermission
+	for _, permission := range permissions {
+		var pairs []types.UserIdGroupPair
+		for _, pair := range permission.UserIdGroupPairs {
+			if slices.Contains(referenced, aws.ToString(pair.GroupId)) {
+				pairs = append(pairs, pair)
+			}
+		}
+		if len(pairs) > 0 {
+			references = append(references, types.IpPermission{
+				IpProtocol:       permission.IpProtocol,
+				FromPort:         permission.FromPort,
+				ToPort:           permission.ToPort,
+				UserIdGroupPairs: pairs,
+			})
+		}
+	}
+	return references
+}
+
+// describeRules describes each group reference in permissions on one line,
+// such as "ingress tcp 443 from sg-0abc1234"
+func describeRules(direction string, permissions []types.IpPermission) []string {
+	preposition := "from"
+	if direction == "egress" {
+		preposition = "to"
+	}
+	var rules []string
+	for _, permission := range permissions {
+		protocol := aws.ToString(permission.IpProtocol)
+		ports := ""
+		switch {
+		case protocol == "-1":
+			protocol = "all"
+		case permission.FromPort == nil:
+		case aws.ToInt32(permission.FromPort) == aws.ToInt32(permission.ToPort):
+			ports = " " + strconv.Itoa(int(aws.ToInt32(permission.FromPort)))
+		default:
+			ports = fmt.Sprintf(" %d-%d", aws.ToInt32(permission.FromPort), aws.ToInt32(permission.ToPort))
+		}
+		for _, pair := range permission.UserIdGroupPairs {
+			rules = append(rules, fmt.Sprintf("%s %s%s %s %s", direction, protocol, ports, preposition, aws.ToString(pair.GroupId)))
+		}
+	}
+	return rules
+}
+
+// Describe the groups among groupIDs that are in a VPC. IDs of groups that
+// are gone or belong to another VPC are left out rather than failing.
+func describeSecurityGroupsByID(ctx context.Context, client EC2API, vpcID string, groupIDs []string) ([]types.SecurityGroup, error) {
+	var sgs []types.SecurityGroup
+	paginator := ec2.NewDescribeSecurityGroupsPaginator(client, &ec2.DescribeSecurityGroupsInput{
+		Filters: []types.Filter{
+			vpcFilter(vpcID),
+			{Name: aws.String("group-id"), Values: groupIDs},
+		},
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		sgs = append(sgs, resp.SecurityGroups...)
+	}
+	return sgs, nil
+}
+
+// pairGroupIDs lists every group a security group's rules name
+func pairGroupIDs(sg types.SecurityGroup) []string {
+	var ids []string
+	for _, permission := range slices.Concat(sg.IpPermissions, sg.IpPermissionsEgress) {
+		for _, pair := range permission.UserIdGroupPairs {
+			if id := aws.ToString(pair.GroupId); !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// Revoke the rules in a security group that refer to other groups in the
+// same VPC, so those groups can be deleted. Only the group and the groups
+// its rules name are described. The revoked rules are returned as
+// describeRules writes them.
+func revokeGroupReferences(ctx context.Context, client EC2API, vpcID, groupID string) ([]string, error) {
+	groups, err := describeSecurityGroupsByID(ctx, client, vpcID, []string{groupID})
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, nil
+	}
+	sg := groups[0]
+	named := pairGroupIDs(sg)
+	if len(named) == 0 {
+		return nil, nil
+	}
+	vpcGroups, err := describeSecurityGroupsByID(ctx, client, vpcID, named)
+	if err != nil {
+		return nil, err
+	}
+	referenced := referencedGroups(sg, securityGroupIDs(vpcGroups))
+
+	var rules []string
+	if ingress := groupReferencePermissions(sg.IpPermissions, referenced); len(ingress) > 0 {
+		if _, err := client.RevokeSecurityGroupIngress(ctx, &ec2.RevokeSecurityGroupIngressInput{GroupId: sg.GroupId, IpPermissions: ingress}); err != nil {
+			return rules, err
+		}
+		rules = append(rules, describeRules("ingress", ingress)...)
+	}
+	if egress := groupReferencePermissions(sg.IpPermissionsEgress, referenced); len(egress) > 0 {
+		if _, err := client.RevokeSecurityGroupEgress(ctx, &ec2.RevokeSecurityGroupEgressInput{GroupId: sg.GroupId, IpPermissions: egress}); err != nil {
+			return rules, err
+		}
+		rules = append(rules, describeRules("egress", egress)...)
+	}
+	return rules, nil
+}
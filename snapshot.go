@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// snapshotVersion is bumped whenever the layout of a snapshot file changes
+const snapshotVersion = 1
+
+// VPCSnapshot is the configuration of a default VPC as EC2 described it
+// before anything in it was deleted
+type VPCSnapshot struct {
+	Vpc              types.Vpc               `json:"vpc"`
+	DhcpOptions      *types.DhcpOptions      `json:"dhcpOptions,omitempty"`
+	InternetGateways []types.InternetGateway `json:"internetGateways"`
+	Subnets          []types.Subnet          `json:"subnets"`
+	RouteTables      []types.RouteTable      `json:"routeTables"`
+	NetworkACLs      []types.NetworkAcl      `json:"networkAcls"`
+	SecurityGroups   []types.SecurityGroup   `json:"securityGroups"`
+}
+
+// RegionSnapshot is written to one file per account and region
+type RegionSnapshot struct {
+	Version   int           `json:"version"`
+	AccountID string        `json:"accountId"`
+	Region    string        `json:"region"`
+	TakenAt   time.Time     `json:"takenAt"`
+	VPCs      []VPCSnapshot `json:"vpcs"`
+}
+
+// SnapshotWriter saves a region's snapshot and returns where it was written
+type SnapshotWriter func(snapshot RegionSnapshot) (string, error)
+
+// newSnapshotWriter returns a SnapshotWriter that writes
+// dir/<account>/<region>-<time>.json. Every region in a run shares takenAt.
+func newSnapshotWriter(dir, accountID string, takenAt time.Time) SnapshotWriter {
+	return func(snapshot RegionSnapshot) (string, error) {
+		snapshot.Version = snapshotVersion
+		snapshot.AccountID = accountID
+		snapshot.TakenAt = takenAt.UTC()
+
+		data, err := json.MarshalIndent(snapshot, "", "  ")
+		if err != nil {
+			return "", err
+		}
+
+		accountDir := filepath.Join(dir, accountID)
+		if err := os.MkdirAll(accountDir, 0o755); err != nil {
+			return "", err
+		}
+		path := filepath.Join(accountDir, fmt.Sprintf("%s-%s.json", snapshot.Region, snapshot.TakenAt.Format("20060102T150405Z")))
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+}
+
+// Look up the account the credentials belong to
+func callerAccountID(ctx context.Context, cfg aws.Config) (string, error) {
+	resp, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the account ID: %w", err)
+	}
+	return aws.ToString(resp.Account), nil
+}
+
+// Describe a single VPC by ID
+func describeVPC(ctx context.Context, client EC2API, vpcID string) (types.Vpc, error) {
+	resp, err := client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+		VpcIds: []string{vpcID},
+	})
+	if err != nil {
+		return types.Vpc{}, fmt.Errorf("failed to describe VPC %s: %w", vpcID, err)
+	}
+	if len(resp.Vpcs) == 0 {
+		return types.Vpc{}, fmt.Errorf("VPC %s not found", vpcID)
+	}
+	return resp.Vpcs[0], nil
+}
+
+// Describe a DHCP options set. VPCs without one report the ID "default".
+func describeDhcpOptions(ctx context.Context, client EC2API, dhcpOptionsID string) (*types.DhcpOptions, error) {
+	if dhcpOptionsID == "" || dhcpOptionsID == "default" {
+		return nil, nil
+	}
+	resp, err := client.DescribeDhcpOptions(ctx, &ec2.DescribeDhcpOptionsInput{
+		DhcpOptionsIds: []string{dhcpOptionsID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe DHCP options %s: %w", dhcpOptionsID, err)
+	}
+	if len(resp.DhcpOptions) == 0 {
+		return nil, nil
+	}
+	return &resp.DhcpOptions[0], nil
+}
+
+// Capture everything about a VPC that cleanupVPCResources and deleteVPC
+// would remove, including the main route table, default network ACL and
+// default security group that go away with the VPC itself
+func snapshotVPC(ctx context.Context, client EC2API, vpcID string) (VPCSnapshot, error) {
+	var snapshot VPCSnapshot
+	var err error
+
+	if snapshot.Vpc, err = describeVPC(ctx, client, vpcID); err != nil {
+		return snapshot, err
+	}
+	if snapshot.DhcpOptions, err = describeDhcpOptions(ctx, client, aws.ToString(snapshot.Vpc.DhcpOptionsId)); err != nil {
+		return snapshot, err
+	}
+	if snapshot.InternetGateways, err = describeInternetGateways(ctx, client, vpcID); err != nil {
+		return snapshot, err
+	}
+	if snapshot.Subnets, err = describeSubnets(ctx, client, vpcID); err != nil {
+		return snapshot, err
+	}
+	if snapshot.RouteTables, err = describeRouteTables(ctx, client, vpcID); err != nil {
+		return snapshot, err
+	}
+	if snapshot.NetworkACLs, err = describeNetworkACLs(ctx, client, vpcID); err != nil {
+		return snapshot, err
+	}
+	if snapshot.SecurityGroups, err = describeSecurityGroups(ctx, client, vpcID); err != nil {
+		return snapshot, err
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func snapshotMock() *MockEC2Client {
+	return &MockEC2Client{
+		describeVpcsFunc: func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+			return &ec2.DescribeVpcsOutput{
+				Vpcs: []types.Vpc{{
+					VpcId:         aws.String(input.VpcIds[0]),
+					CidrBlock:     aws.String("172.31.0.0/16"),
+					DhcpOptionsId: aws.String("dopt-12345"),
+					IsDefault:     aws.Bool(true),
+				}},
+			}, nil
+		},
+		describeDhcpOptionsFunc: func(ctx context.Context, input *ec2.DescribeDhcpOptionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeDhcpOptionsOutput, error) {
+			return &ec2.DescribeDhcpOptionsOutput{
+				DhcpOptions: []types.DhcpOptions{{DhcpOptionsId: aws.String("dopt-12345")}},
+			}, nil
+		},
+		describeInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
+			return &ec2.DescribeInternetGatewaysOutput{InternetGateways: []types.InternetGateway{{InternetGatewayId: aws.String("igw-12345")}}}, nil
+		},
+		describeSubnetsFunc: func(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+			return &ec2.DescribeSubnetsOutput{
+				Subnets: []types.Subnet{{SubnetId: aws.String("subnet-12345"), AvailabilityZone: aws.String("us-east-1a"), CidrBlock: aws.String("172.31.0.0/20")}},
+			}, nil
+		},
+		describeRouteTablesFunc: func(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+			return &ec2.DescribeRouteTablesOutput{
+				RouteTables: []types.RouteTable{{
+					RouteTableId: aws.String("rtb-12345"),
+					Associations: []types.RouteTableAssociation{{Main: aws.Bool(true)}},
+					Routes:       []types.Route{{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-12345")}},
+				}},
+			}, nil
+		},
+		describeNatGatewaysFunc: func(ctx context.Context, input *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+			return &ec2.DescribeNatGatewaysOutput{}, nil
+		},
+		describeVpcEndpointsFunc: func(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+			return &ec2.DescribeVpcEndpointsOutput{}, nil
+		},
+		describeNetworkInterfacesFunc: func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+			return &ec2.DescribeNetworkInterfacesOutput{}, nil
+		},
+		describeEgressOnlyInternetGatewaysFunc: func(ctx context.Context, input *ec2.DescribeEgressOnlyInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+			return &ec2.DescribeEgressOnlyInternetGatewaysOutput{}, nil
+		},
+		describeNetworkAclsFunc: func(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
+			return &ec2.DescribeNetworkAclsOutput{NetworkAcls: []types.NetworkAcl{{NetworkAclId: aws.String("acl-12345"), IsDefault: aws.Bool(true)}}}, nil
+		},
+		describeSecurityGroupsFunc: func(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+			return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: []types.SecurityGroup{{GroupId: aws.String("sg-12345"), GroupName: aws.String("default")}}}, nil
+		},
+	}
+}
+
+func Test_snapshotVPC(t *testing.T) {
+	dhcpFails := snapshotMock()
+	dhcpFails.describeDhcpOptionsFunc = func(ctx context.Context, input *ec2.DescribeDhcpOptionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeDhcpOptionsOutput, error) {
+		return nil, fmt.Errorf("UnauthorizedOperation")
+	}
+
+	tests := []struct {
+		name    string
+		client  EC2API
+		wantErr bool
+	}{
+		{
+			name:   "everything is captured, including defaults",
+			client: snapshotMock(),
+		},
+		{
+			name:    "error describing DHCP options",
+			client:  dhcpFails,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := snapshotVPC(context.Background(), tt.client, "vpc-12345")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("snapshotVPC() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if aws.ToString(got.Vpc.CidrBlock) != "172.31.0.0/16" || got.DhcpOptions == nil {
+				t.Errorf("snapshotVPC() VPC = %+v, DHCP options = %+v", got.Vpc, got.DhcpOptions)
+			}
+			if len(got.InternetGateways) != 1 || len(got.Subnets) != 1 || len(got.RouteTables) != 1 || len(got.NetworkACLs) != 1 || len(got.SecurityGroups) != 1 {
+				t.Errorf("snapshotVPC() = %+v, want one of each resource", got)
+			}
+		})
+	}
+}
+
+func Test_newSnapshotWriter(t *testing.T) {
+	dir := t.TempDir()
+	takenAt := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	backup := newSnapshotWriter(dir, "222222222222", takenAt)
+
+	vpc, err := snapshotVPC(context.Background(), snapshotMock(), "vpc-12345")
+	if err != nil {
+		t.Fatalf("snapshotVPC() error = %v", err)
+	}
+	path, err := backup(RegionSnapshot{Region: "us-east-1", VPCs: []VPCSnapshot{vpc}})
+	if err != nil {
+		t.Fatalf("SnapshotWriter() error = %v", err)
+	}
+	if want := filepath.Join(dir, "222222222222", "us-east-1-20240501T123000Z.json"); path != want {
+		t.Errorf("SnapshotWriter() path = %v, want %v", path, want)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading snapshot: %v", err)
+	}
+	var got RegionSnapshot
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("snapshot is not valid JSON: %v", err)
+	}
+	if got.Version != snapshotVersion || got.AccountID != "222222222222" || !got.TakenAt.Equal(takenAt) {
+		t.Errorf("snapshot header = %d %s %v", got.Version, got.AccountID, got.TakenAt)
+	}
+	if len(got.VPCs) != 1 || aws.ToString(got.VPCs[0].RouteTables[0].Routes[0].GatewayId) != "igw-12345" {
+		t.Errorf("snapshot VPCs = %+v", got.VPCs)
+	}
+}
+
+func Test_deleteRegionDefaultVPCs_snapshotFails(t *testing.T) {
+	client := snapshotMock()
+	client.describeInstancesFunc = func(ctx context.Context, input *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+		return &ec2.DescribeInstancesOutput{}, nil
+	}
+	client.describeNetworkInterfacesFunc = func(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+		return &ec2.DescribeNetworkInterfacesOutput{}, nil
+	}
+	client.describeVpcsFunc = func(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+		return &ec2.DescribeVpcsOutput{Vpcs: []types.Vpc{{VpcId: aws.String("vpc-12345"), IsDefault: aws.Bool(true)}}}, nil
+	}
+	client.detachInternetGatewayFunc = func(ctx context.Context, input *ec2.DetachInternetGatewayInput, optFns ...func(*ec2.Options)) (*ec2.DetachInternetGatewayOutput, error) {
+		t.Errorf("detached internet gateway %s without a snapshot", aws.ToString(input.InternetGatewayId))
+		return &ec2.DetachInternetGatewayOutput{}, nil
+	}
+
+	result := deleteRegionDefaultVPCs(context.Background(), client, "us-east-1", Protection{}, CleanupOptions{}, func(snapshot RegionSnapshot) (string, error) {
+		return "", fmt.Errorf("disk full")
+	})
+	if result.Err == nil || len(result.VPCs) != 0 {
+		t.Errorf("deleteRegionDefaultVPCs() = %+v, want a region error and no VPCs touched", result)
+	}
+}
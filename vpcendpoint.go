@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+// Delete a VPC endpoint and wait until it has gone, as an interface
+// endpoint's network interfaces hold up its subnets until then
+func deleteVpcEndpoint(ctx context.Context, client EC2API, endpointID string) error {
+	out, err := client.DeleteVpcEndpoints(ctx, &ec2.DeleteVpcEndpointsInput{VpcEndpointIds: []string{endpointID}})
+	if err != nil {
+		return err
+	}
+	// DeleteVpcEndpoints reports failures per endpoint rather than as an error
+	for _, item := range out.Unsuccessful {
+		if item.Error != nil {
+			return &smithy.GenericAPIError{Code: aws.ToString(item.Error.Code), Message: aws.ToString(item.Error.Message)}
+		}
+	}
+	loggerFrom(ctx).Info("waiting for VPC endpoint to be deleted", logKeyResourceType, resourceVpcEndpoint, logKeyResourceID, endpointID)
+	return waitUntil(ctx, deleteWaitTimeout, deleteWaitInterval, func() (bool, error) {
+		out, err := client.DescribeVpcEndpoints(ctx, &ec2.DescribeVpcEndpointsInput{VpcEndpointIds: []string{endpointID}})
+		if isNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		for _, endpoint := range out.VpcEndpoints {
+			if endpoint.State != types.StateDeleted {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}